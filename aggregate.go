@@ -0,0 +1,101 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/destel/rill"
+)
+
+// number is satisfied by the types of all aggregated usage metrics.
+type number interface {
+	~uint64 | ~float64
+}
+
+// sum is a merge function that totals metric values.
+func sum[T number](a, b T) T {
+	return a + b
+}
+
+// greatest is a merge function that keeps the largest metric value.
+func greatest[T number](a, b T) T {
+	if b > a {
+		return b
+	}
+	return a
+}
+
+// concurrency returns the number of VDCs to query at once, as limited by the client the VDCs
+// belong to.
+func (vdcs VDCs) concurrency() int {
+	if len(vdcs) == 0 {
+		return 1
+	}
+	return min(vdcs[0].Client.concurrency(), len(vdcs))
+}
+
+// each retrieves a value from every VDC concurrently, preserving the order of the VDCs. Values are
+// collected by a single consumer. Failures are returned separately, each identifying the VDC it
+// belongs to.
+func each[T any](ctx context.Context, vdcs VDCs, get func(*VDC, context.Context) (T, error)) ([]T, []error) {
+	started := time.Now()
+	results := rill.OrderedMap(rill.FromSlice(vdcs, nil), vdcs.concurrency(), func(vdc VDC) (T, error) {
+		value, err := get(&vdc, ctx)
+		if err != nil {
+			return value, vdc.wrapError(err)
+		}
+		return value, nil
+	})
+	values := make([]T, 0, len(vdcs))
+	errs := make([]error, 0)
+	for res := range results {
+		if res.Error != nil {
+			errs = append(errs, res.Error)
+			continue
+		}
+		values = append(values, res.Value)
+	}
+	if len(vdcs) != 0 {
+		vdcs[0].Client.log(ctx, "aggregated VDCs",
+			slog.Int("vdcs", len(vdcs)),
+			slog.Int("failed", len(errs)),
+			slog.Int("concurrency", vdcs.concurrency()),
+			slog.Duration("duration", time.Since(started)),
+		)
+	}
+	return values, errs
+}
+
+// reduce retrieves a metric from every VDC concurrently and combines the values of all VDCs for
+// which the metric was retrieved successfully with merge. Failures are returned separately, each
+// identifying the VDC it belongs to.
+func reduce[T number](
+	ctx context.Context,
+	vdcs VDCs,
+	get func(*VDC, context.Context) (T, error),
+	merge func(T, T) T,
+) (T, []error) {
+	values, errs := each(ctx, vdcs, get)
+	var total T
+	for _, value := range values {
+		total = merge(total, value)
+	}
+	return total, errs
+}
+
+// collect is the same as reduce, but if retrieving the metric fails for any VDC, no partial value
+// is returned; instead, the failures of all VDCs are joined.
+func collect[T number](
+	ctx context.Context,
+	vdcs VDCs,
+	get func(*VDC, context.Context) (T, error),
+	merge func(T, T) T,
+) (T, error) {
+	total, errs := reduce(ctx, vdcs, get, merge)
+	if len(errs) != 0 {
+		return 0, errors.Join(errs...)
+	}
+	return total, nil
+}
@@ -0,0 +1,130 @@
+package vcdusage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+func testVDCs(count, concurrency int) VDCs {
+	client := &Client{opts: &Options{Concurrency: concurrency}}
+	vdcs := make(VDCs, 0, count)
+	for i := 0; i < count; i++ {
+		obj := &govcd.Vdc{Vdc: &types.Vdc{ID: fmt.Sprintf("vdc-%d", i), Name: fmt.Sprintf("VDC %d", i)}}
+		vdcs = append(vdcs, VDC{Obj: obj, Client: client})
+	}
+	return vdcs
+}
+
+func Test_collect(t *testing.T) {
+	t.Run("sum", func(t *testing.T) {
+		t.Parallel()
+		vdcs := testVDCs(500, 16)
+		get := func(vdc *VDC, _ context.Context) (uint64, error) {
+			return 2, nil
+		}
+		for i := 0; i < 20; i++ {
+			total, err := collect(context.Background(), vdcs, get, sum[uint64])
+			require.NoError(t, err)
+			assert.Equal(t, uint64(1_000), total)
+		}
+	})
+	t.Run("no cpu speed", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		vdcs := testVDCs(2, 2)
+		client := vdcs[0].Client
+		client.opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		client.cache = newCache(time.Minute)
+		speed := int64(2000)
+		for i, speed := range []*int64{&speed, nil} {
+			avdc := &govcd.AdminVdc{AdminVdc: &types.AdminVdc{
+				Vdc:        types.Vdc{ComputeCapacity: []*types.ComputeCapacity{{CPU: &types.CapacityWithUsage{Units: "MHz", Used: 8000}}}},
+				VCpuInMhz2: speed,
+			}}
+			client.cache.store(vdcs[i].Obj.Vdc.ID, avdc, 0)
+		}
+		cores, err := vdcs.CoreCountContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(4), cores)
+		maxSpeed, err := vdcs.SpeedContext(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2000), maxSpeed)
+		assert.Contains(t, buf.String(), `msg="excluding VDC without vCPU speed from total" vdc_id=vdc-1 vdc_name="VDC 1" method=CoreCount`)
+		_, err = vdcs[1].CoreCountContext(context.Background())
+		assert.ErrorIs(t, err, ErrNoCPUSpeed)
+	})
+	t.Run("greatest", func(t *testing.T) {
+		t.Parallel()
+		vdcs := testVDCs(100, 16)
+		get := func(vdc *VDC, _ context.Context) (uint64, error) {
+			var n uint64
+			fmt.Sscanf(vdc.Obj.Vdc.ID, "vdc-%d", &n)
+			return n, nil
+		}
+		speed, err := collect(context.Background(), vdcs, get, greatest[uint64])
+		require.NoError(t, err)
+		assert.Equal(t, uint64(99), speed)
+	})
+	t.Run("concurrency limit", func(t *testing.T) {
+		t.Parallel()
+		vdcs := testVDCs(50, 4)
+		var inFlight, peak atomic.Int64
+		get := func(vdc *VDC, _ context.Context) (DataStorage, error) {
+			n := inFlight.Add(1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			inFlight.Add(-1)
+			return 1.5, nil
+		}
+		total, err := collect(context.Background(), vdcs, get, sum[DataStorage])
+		require.NoError(t, err)
+		assert.Equal(t, DataStorage(75), total)
+		assert.LessOrEqual(t, peak.Load(), int64(4))
+	})
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		vdcs := testVDCs(10, 4)
+		errFailed := errors.New("failed")
+		get := func(vdc *VDC, _ context.Context) (uint64, error) {
+			if vdc.Obj.Vdc.ID == "vdc-3" || vdc.Obj.Vdc.ID == "vdc-7" {
+				return 0, errFailed
+			}
+			return 1, nil
+		}
+		total, err := collect(context.Background(), vdcs, get, sum[uint64])
+		assert.Zero(t, total, "partial total returned")
+		assert.ErrorIs(t, err, errFailed)
+		var vdcErr *VDCError
+		require.ErrorAs(t, err, &vdcErr)
+		assert.Contains(t, []string{"vdc-3", "vdc-7"}, vdcErr.ID)
+
+		partial, errs := reduce(context.Background(), vdcs, get, sum[uint64])
+		assert.Equal(t, uint64(8), partial)
+		assert.Len(t, errs, 2)
+	})
+	t.Run("empty", func(t *testing.T) {
+		t.Parallel()
+		get := func(vdc *VDC, _ context.Context) (uint64, error) {
+			return 1, nil
+		}
+		total, err := collect(context.Background(), VDCs{}, get, sum[uint64])
+		require.NoError(t, err)
+		assert.Zero(t, total)
+	})
+}
@@ -0,0 +1,156 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/destel/rill"
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// BulkSnapshot retrieves the usage of every VDC in every organization, the same as
+// ProviderSnapshot, but retrieves the VDCs, storage profiles, and VMs of all organizations with
+// paged typed queries instead of requests for each organization, VDC, and storage profile. The
+// Query API does not report the vCPU speed of a VDC, so the admin VDC of each VDC is still
+// retrieved to calculate its core count: a bulk snapshot makes one request per VDC in addition to
+// the paged queries, unless the admin VDC is cached with CacheTTL. Bulk queries are only available
+// to system administrators.
+//
+// If the usage of any VDC cannot be calculated, or its organization is not one of the listed
+// organizations, e.g. because it was created during the snapshot, the report of the remaining
+// VDCs is returned with an error identifying each such VDC.
+func (client *Client) BulkSnapshot() (*ProviderReport, error) {
+	return client.BulkSnapshotContext(context.Background())
+}
+
+// BulkSnapshotContext is the same as BulkSnapshot, but aborts if ctx is cancelled.
+func (client *Client) BulkSnapshotContext(ctx context.Context) (_ *ProviderReport, err error) {
+	ctx, span := client.startSpan(ctx, "Client.BulkSnapshot")
+	defer func() { endSpan(span, err) }()
+	started := time.Now()
+	refs, err := client.orgRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	vdcs, err := queryAll(ctx, client, types.QtAdminOrgVdc, "", func(res *types.QueryResultRecordsType) []*types.QueryResultOrgVdcRecordType {
+		return res.OrgVdcAdminRecord
+	})
+	if err != nil {
+		return nil, err
+	}
+	records, err := queryAll(ctx, client, types.QtAdminOrgVdcStorageProfile, "", func(res *types.QueryResultRecordsType) []*types.QueryResultAdminOrgVdcStorageProfileRecordType {
+		return res.AdminOrgVdcStorageProfileRecord
+	})
+	if err != nil {
+		return nil, err
+	}
+	profiles := make(map[string][]*types.VdcStorageProfile)
+	for _, rec := range records {
+		id := uuidOf(rec.Vdc)
+		profiles[id] = append(profiles[id], profileOf(rec))
+	}
+	vmRecords, err := queryAll(ctx, client, types.QtAdminVm, "", func(res *types.QueryResultRecordsType) []*types.QueryResultVMRecordType {
+		return res.AdminVMRecord
+	})
+	if err != nil {
+		return nil, err
+	}
+	vms := make(map[string][]*types.QueryResultVMRecordType)
+	for _, vm := range vmRecords {
+		id := uuidOf(vm.VdcHREF)
+		vms[id] = append(vms[id], vm)
+	}
+	results := rill.OrderedMap(rill.FromSlice(vdcs, nil), client.concurrency(), func(rec *types.QueryResultOrgVdcRecordType) (*UsageSnapshot, error) {
+		id := uuidOf(rec.HREF)
+		avdc, err := client.bulkAdminVDC(ctx, rec)
+		if err != nil {
+			return nil, &VDCError{ID: urnOf("vdc", id), Name: rec.Name, OrgID: urnOf("org", rec.Org), Err: err}
+		}
+		snap := usageOf(avdc, profiles[id], vms[id])
+		snap.VDCID = urnOf("vdc", id)
+		snap.VDCName = rec.Name
+		snap.OrgID = urnOf("org", rec.Org)
+		snap.OrgName = rec.OrgName
+		snap.StartedAt = started
+		snap.CollectedAt = time.Now()
+		return snap, nil
+	})
+	listed := make(map[string]bool, len(refs))
+	for _, ref := range refs {
+		listed[urnOf("org", ref.ID)] = true
+	}
+	byOrg := make(map[string][]*UsageSnapshot)
+	errs := make([]error, 0)
+	for res := range results {
+		if res.Error != nil {
+			errs = append(errs, res.Error)
+			continue
+		}
+		snap := res.Value
+		if !listed[snap.OrgID] {
+			err := fmt.Errorf("%w: org '%s' is not listed", ErrOrgNotFound, snap.OrgName)
+			errs = append(errs, &VDCError{ID: snap.VDCID, Name: snap.VDCName, OrgID: snap.OrgID, Err: err})
+			continue
+		}
+		byOrg[snap.OrgID] = append(byOrg[snap.OrgID], snap)
+	}
+	report := &ProviderReport{
+		Orgs:      make([]*OrgReport, 0, len(refs)),
+		StartedAt: started,
+	}
+	for _, ref := range refs {
+		id := urnOf("org", ref.ID)
+		snaps := byOrg[id]
+		if snaps == nil {
+			snaps = make([]*UsageSnapshot, 0)
+		}
+		orgReport := newOrgReport(id, ref.Name, snaps, started)
+		report.Orgs = append(report.Orgs, orgReport)
+		report.Total.merge(orgReport.Total)
+	}
+	report.CollectedAt = time.Now()
+	if len(errs) != 0 {
+		return report, errors.Join(errs...)
+	}
+	return report, nil
+}
+
+// bulkAdminVDC retrieves the admin representation of the VDC of a query record, from the client's
+// cache if enabled.
+func (client *Client) bulkAdminVDC(ctx context.Context, rec *types.QueryResultOrgVdcRecordType) (*govcd.AdminVdc, error) {
+	id := urnOf("vdc", rec.HREF)
+	avdc, err := cached(ctx, client, id, func(ctx context.Context) (*govcd.AdminVdc, error) {
+		return call(ctx, client, "GetAdminVDCByHref", func() (*govcd.AdminVdc, error) {
+			avdc := govcd.NewAdminVdc(&client.VCD.Client)
+			_, err := client.VCD.Client.ExecuteRequest(rec.HREF, http.MethodGet, "", "error getting vdc: %s", nil, avdc.AdminVdc)
+			if err != nil {
+				return nil, err
+			}
+			return avdc, nil
+		}, slog.String("vdc_id", id), slog.String("vdc_name", rec.Name))
+	})
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve admin VDC '%s'", id)
+		return nil, err
+	}
+	return avdc, nil
+}
+
+// profileOf converts a storage profile query record to a storage profile.
+func profileOf(rec *types.QueryResultAdminOrgVdcStorageProfileRecordType) *types.VdcStorageProfile {
+	return &types.VdcStorageProfile{
+		ID:            urnOf("vdcstorageProfile", rec.HREF),
+		Name:          rec.Name,
+		Enabled:       &rec.IsEnabled,
+		Units:         "MB",
+		Limit:         int64(rec.StorageLimitMB),
+		Default:       rec.IsDefaultStorageProfile,
+		StorageUsedMB: int64(rec.StorageUsedMB),
+	}
+}
@@ -0,0 +1,323 @@
+package vcdusage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+const (
+	testOrgA = "aaaaaaaa-0000-0000-0000-000000000001"
+	testOrgB = "bbbbbbbb-0000-0000-0000-000000000002"
+	testOrgC = "cccccccc-0000-0000-0000-000000000003"
+	testVDC1 = "11111111-0000-0000-0000-000000000001"
+	testVDC2 = "22222222-0000-0000-0000-000000000002"
+)
+
+// testQueryServer is a fake vCloud API serving an org list, admin VDCs, and typed queries, which
+// returns at most pageSize records per page. It records the requests it serves, responds to
+// requests for the paths in notFound as if the objects had been deleted, and fails requests for
+// the paths in failing. The orgs in unlisted are left out of the org list. VMs are served after
+// delay, and peak is the largest number of requests it has served at once.
+type testQueryServer struct {
+	*httptest.Server
+	pageSize int
+	notFound []string
+	failing  []string
+	unlisted []string
+	delay    time.Duration
+	mu       sync.Mutex
+	requests []string
+	active   int
+	peak     int
+}
+
+// testQueryRecords are the records served for each query type as XML elements, with %[1]s as a
+// placeholder for the server's API URL.
+var testQueryRecords = map[string][]string{
+	"adminOrgVdc": {
+		`<AdminVdcRecord href="%[1]s/vdc/` + testVDC1 + `" name="vdc-1" org="%[1]s/org/` + testOrgA + `" orgName="org-a"/>`,
+		`<AdminVdcRecord href="%[1]s/vdc/` + testVDC2 + `" name="vdc-2" org="%[1]s/org/` + testOrgB + `" orgName="org-b"/>`,
+	},
+	"adminOrgVdcStorageProfile": {
+		`<AdminOrgVdcStorageProfileRecord href="%[1]s/vdcStorageProfile/sp-1" name="fast" isDefaultStorageProfile="true" storageUsedMB="1024" storageLimitMB="4096" vdc="%[1]s/vdc/` + testVDC1 + `"/>`,
+		`<AdminOrgVdcStorageProfileRecord href="%[1]s/vdcStorageProfile/sp-2" name="slow" storageUsedMB="512" storageLimitMB="8192" vdc="%[1]s/vdc/` + testVDC1 + `"/>`,
+		`<AdminOrgVdcStorageProfileRecord href="%[1]s/vdcStorageProfile/sp-3" name="fast" isDefaultStorageProfile="true" storageUsedMB="2048" storageLimitMB="4096" vdc="%[1]s/vdc/` + testVDC2 + `"/>`,
+	},
+	"adminVM": {
+		`<AdminVMRecord href="%[1]s/vApp/vm-1" name="web-1" status="POWERED_ON" isVAppTemplate="false" guestOs="Ubuntu Linux (64-bit)" numberOfCpus="2" memoryMB="4096" container="%[1]s/vApp/vapp-1" containerName="web" hardwareVersion="19" storageProfileName="fast" dateCreated="2024-03-01T12:30:00.000Z" totalStorageAllocatedMb="20480" vdc="%[1]s/vdc/` + testVDC1 + `" vdcName="vdc-1"/>`,
+		`<AdminVMRecord href="%[1]s/vApp/vm-2" name="web-2" status="POWERED_OFF" isVAppTemplate="false" guestOs="Ubuntu Linux (64-bit)" numberOfCpus="2" vdc="%[1]s/vdc/` + testVDC1 + `"/>`,
+		`<AdminVMRecord href="%[1]s/vApp/vm-3" name="db-1" status="POWERED_ON" isVAppTemplate="false" guestOs="Microsoft Windows Server 2022 (64-bit)" numberOfCpus="4" vdc="%[1]s/vdc/` + testVDC1 + `"/>`,
+		`<AdminVMRecord href="%[1]s/vApp/vm-4" name="template" status="POWERED_OFF" isVAppTemplate="true" numberOfCpus="8" vdc="%[1]s/vdc/` + testVDC1 + `"/>`,
+		`<AdminVMRecord href="%[1]s/vApp/vm-5" name="app-1" status="POWERED_ON" isVAppTemplate="false" guestOs="Microsoft Windows Server 2022 (64-bit)" numberOfCpus="1" vdc="%[1]s/vdc/` + testVDC2 + `"/>`,
+	},
+}
+
+// testVMDetails are the VMs served by ID as XML documents, and the used storage in KB reported by
+// their metrics. VMs that are not listed are not found.
+var testVMDetails = map[string]struct {
+	vm   string
+	used int
+}{
+	"vm-1": {`<Vm name="web-1"><VmSpecSection><NumCoresPerSocket>2</NumCoresPerSocket></VmSpecSection></Vm>`, 10 * kb * kb},
+	"vm-2": {`<Vm name="web-2"><VmSpecSection><NumCoresPerSocket>1</NumCoresPerSocket></VmSpecSection></Vm>`, 0},
+	"vm-5": {`<Vm name="app-1"><VmSpecSection><NumCoresPerSocket>1</NumCoresPerSocket></VmSpecSection></Vm>`, kb * kb},
+}
+
+// testAdminVDCs are the admin VDCs served by ID as XML documents.
+var testAdminVDCs = map[string]string{
+	testVDC1: `<AdminVdc id="urn:vcloud:vdc:` + testVDC1 + `" name="vdc-1"><ComputeCapacity><Cpu><Units>MHz</Units><Used>8000</Used></Cpu><Memory><Units>MB</Units><Used>8192</Used></Memory></ComputeCapacity><VCpuInMhz2>2000</VCpuInMhz2></AdminVdc>`,
+	testVDC2: `<AdminVdc id="urn:vcloud:vdc:` + testVDC2 + `" name="vdc-2"><ComputeCapacity><Cpu><Units>MHz</Units><Used>3000</Used></Cpu><Memory><Units>MB</Units><Used>2048</Used></Memory></ComputeCapacity><VCpuInMhz2>1000</VCpuInMhz2></AdminVdc>`,
+}
+
+func newTestQueryServer(t *testing.T, pageSize int) *testQueryServer {
+	t.Helper()
+	srv := &testQueryServer{pageSize: pageSize}
+	srv.Server = httptest.NewTLSServer(http.HandlerFunc(srv.serve))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (srv *testQueryServer) serve(w http.ResponseWriter, r *http.Request) {
+	srv.mu.Lock()
+	srv.requests = append(srv.requests, r.URL.RequestURI())
+	srv.active++
+	srv.peak = max(srv.peak, srv.active)
+	srv.mu.Unlock()
+	defer func() {
+		srv.mu.Lock()
+		srv.active--
+		srv.mu.Unlock()
+	}()
+	if slices.Contains(srv.notFound, r.URL.Path) {
+		writeTestError(w, http.StatusNotFound)
+		return
+	}
+	if slices.Contains(srv.failing, r.URL.Path) {
+		writeTestError(w, http.StatusInternalServerError)
+		return
+	}
+	api := srv.URL + "/api"
+	w.Header().Set("Content-Type", "application/*+xml")
+	switch {
+	case r.URL.Path == "/api/org":
+		fmt.Fprint(w, "<OrgList>")
+		for name, id := range map[string]string{"org-a": testOrgA, "org-b": testOrgB, "org-c": testOrgC} {
+			if slices.Contains(srv.unlisted, name) {
+				continue
+			}
+			fmt.Fprintf(w, `<Org href="%s/org/%s" name="%s"/>`, api, id, name)
+		}
+		fmt.Fprint(w, "</OrgList>")
+	case strings.HasPrefix(r.URL.Path, "/api/vdc/"):
+		avdc, ok := testAdminVDCs[strings.TrimPrefix(r.URL.Path, "/api/vdc/")]
+		if !ok {
+			writeTestError(w, http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, avdc)
+	case strings.HasPrefix(r.URL.Path, "/api/vApp/"):
+		time.Sleep(srv.delay)
+		id, metrics := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/api/vApp/"), "/metrics/current")
+		details, ok := testVMDetails[id]
+		switch {
+		case !ok:
+			writeTestError(w, http.StatusNotFound)
+		case metrics:
+			fmt.Fprintf(w, `<CurrentUsage><Metric name="cpu.usage.average" unit="PERCENT" value="2.5"/><Metric name="disk.used.latest" unit="KILOBYTE" value="%d"/></CurrentUsage>`, details.used)
+		default:
+			fmt.Fprint(w, details.vm)
+		}
+	case r.URL.Path == "/api/query":
+		records := srv.filter(testQueryRecords[r.URL.Query().Get("type")], rawQueryValue(r.URL.RawQuery, "filter"))
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		start := min((page-1)*srv.pageSize, len(records))
+		end := min(start+srv.pageSize, len(records))
+		fmt.Fprintf(w, `<QueryResultRecords page="%d" pageSize="%d" total="%d">`, page, srv.pageSize, len(records))
+		for _, rec := range records[start:end] {
+			fmt.Fprintf(w, rec, api)
+		}
+		fmt.Fprint(w, "</QueryResultRecords>")
+	default:
+		writeTestError(w, http.StatusNotFound)
+	}
+}
+
+// writeTestError responds with status and a vCloud error document, as vCloud does.
+func writeTestError(w http.ResponseWriter, status int) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `<Error majorErrorCode="%d" message="%s" minorErrorCode="%s"/>`, status, http.StatusText(status), http.StatusText(status))
+}
+
+// rawQueryValue returns the unparsed value of key in query. Filters contain semicolons, which are
+// rejected by url.ParseQuery.
+func rawQueryValue(query, key string) string {
+	for _, param := range strings.Split(query, "&") {
+		if k, v, ok := strings.Cut(param, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// filter returns the records matching every condition of a filter of the form
+// 'attr==value;attr==value', where values may contain wildcards and are matched
+// case-insensitively, as vCloud does, or all records if the filter is empty.
+func (srv *testQueryServer) filter(records []string, filter string) []string {
+	if filter == "" {
+		return records
+	}
+	patterns := make([]*regexp.Regexp, 0)
+	for _, cond := range strings.Split(filter, ";") {
+		attr, value, _ := strings.Cut(cond, "==")
+		value, _ = url.QueryUnescape(value)
+		parts := strings.Split(value, "*")
+		for i, part := range parts {
+			parts[i] = regexp.QuoteMeta(part)
+		}
+		patterns = append(patterns, regexp.MustCompile(fmt.Sprintf(`(?i)\b%s="%s"`, attr, strings.Join(parts, `[^"]*`))))
+	}
+	matched := make([]string, 0, len(records))
+	for _, rec := range records {
+		ok := true
+		for _, pattern := range patterns {
+			ok = ok && pattern.MatchString(fmt.Sprintf(rec, srv.URL+"/api"))
+		}
+		if ok {
+			matched = append(matched, rec)
+		}
+	}
+	return matched
+}
+
+// queries returns the number of query requests served for queryType.
+func (srv *testQueryServer) queries(queryType string) int {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	count := 0
+	for _, req := range srv.requests {
+		if strings.Contains(req, "type="+queryType+"&") || strings.HasSuffix(req, "type="+queryType) {
+			count++
+		}
+	}
+	return count
+}
+
+// client returns a client of the server.
+func (srv *testQueryServer) client(t *testing.T) *Client {
+	t.Helper()
+	u, err := ParseURL(srv.URL)
+	require.NoError(t, err)
+	vcd := govcd.NewVCDClient(*u, true)
+	vcd.Client.APIVersion = MaxAPIVersion
+	vcd.Client.IsSysAdmin = true
+	return &Client{VCD: vcd, opts: &Options{Concurrency: 2}}
+}
+
+func Test_queryPages(t *testing.T) {
+	srv := newTestQueryServer(t, 2)
+	client := srv.client(t)
+	pages := 0
+	names := make([]string, 0)
+	err := client.queryPages(context.Background(), "adminVM", "", func(res *types.QueryResultRecordsType) error {
+		pages++
+		for _, vm := range res.AdminVMRecord {
+			names = append(names, vm.Name)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{"web-1", "web-2", "db-1", "template", "app-1"}, names)
+	assert.Equal(t, 3, srv.queries("adminVM"))
+}
+
+func Test_BulkSnapshot(t *testing.T) {
+	srv := newTestQueryServer(t, 2)
+	client := srv.client(t)
+	report, err := client.BulkSnapshotContext(context.Background())
+	require.NoError(t, err)
+	require.Len(t, report.Orgs, 3)
+	orgs := make(map[string]*OrgReport)
+	for _, org := range report.Orgs {
+		orgs[org.Name] = org
+	}
+	require.Len(t, orgs["org-a"].VDCs, 1)
+	vdc1 := orgs["org-a"].VDCs[0]
+	assert.Equal(t, "urn:vcloud:vdc:"+testVDC1, vdc1.VDCID)
+	assert.Equal(t, "urn:vcloud:org:"+testOrgA, vdc1.OrgID)
+	assert.Equal(t, uint64(2000), vdc1.Speed)
+	assert.Equal(t, uint64(4), vdc1.Cores)
+	assert.Equal(t, DataStorage(8192*mb), vdc1.Memory)
+	assert.Equal(t, DataStorage(1024*mb), vdc1.Storage)
+	assert.Equal(t, DataStorage(1536*mb), vdc1.StorageAll)
+	assert.Len(t, vdc1.StorageProfiles, 2)
+	assert.Equal(t, uint64(3), vdc1.VMCount)
+	assert.Equal(t, uint64(2), vdc1.PoweredOnVMCount)
+	assert.Equal(t, uint64(4), vdc1.VMCoreCountWithQuery(VMWithGuestOSContaining("windows")))
+	require.Len(t, orgs["org-b"].VDCs, 1)
+	assert.Equal(t, uint64(3), orgs["org-b"].Total.Cores)
+	assert.Empty(t, orgs["org-c"].VDCs)
+	assert.Equal(t, UsageTotals{
+		Cores:            7,
+		Memory:           DataStorage(10240 * mb),
+		Storage:          DataStorage(3072 * mb),
+		StorageAll:       DataStorage(3584 * mb),
+		VMCount:          4,
+		PoweredOnVMCount: 3,
+	}, report.Total)
+	assert.Equal(t, 1, srv.queries("adminOrgVdc"))
+	assert.Equal(t, 2, srv.queries("adminOrgVdcStorageProfile"))
+	assert.Equal(t, 3, srv.queries("adminVM"))
+}
+
+func Test_BulkSnapshotPartial(t *testing.T) {
+	srv := newTestQueryServer(t, 2)
+	srv.notFound = []string{"/api/vdc/" + testVDC2}
+	client := srv.client(t)
+	report, err := client.BulkSnapshotContext(context.Background())
+	require.Error(t, err)
+	var vdcErr *VDCError
+	require.ErrorAs(t, err, &vdcErr)
+	assert.Equal(t, "urn:vcloud:vdc:"+testVDC2, vdcErr.ID)
+	assert.Equal(t, "vdc-2", vdcErr.Name)
+	require.NotNil(t, report)
+	require.Len(t, report.Orgs, 3)
+	orgs := make(map[string]*OrgReport)
+	for _, org := range report.Orgs {
+		orgs[org.Name] = org
+	}
+	require.Len(t, orgs["org-a"].VDCs, 1)
+	assert.Empty(t, orgs["org-b"].VDCs)
+	assert.Equal(t, uint64(4), report.Total.Cores)
+	assert.Equal(t, uint64(3), report.Total.VMCount)
+}
+
+func Test_BulkSnapshotUnlistedOrg(t *testing.T) {
+	srv := newTestQueryServer(t, 2)
+	srv.unlisted = []string{"org-b"}
+	client := srv.client(t)
+	report, err := client.BulkSnapshotContext(context.Background())
+	require.ErrorIs(t, err, ErrOrgNotFound)
+	var vdcErr *VDCError
+	require.ErrorAs(t, err, &vdcErr)
+	assert.Equal(t, "urn:vcloud:vdc:"+testVDC2, vdcErr.ID)
+	assert.Equal(t, "urn:vcloud:org:"+testOrgB, vdcErr.OrgID)
+	require.NotNil(t, report)
+	require.Len(t, report.Orgs, 2)
+	assert.Equal(t, uint64(4), report.Total.Cores)
+}
@@ -0,0 +1,169 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCfgCacheTTL indicates a cache TTL is negative.
+var ErrCfgCacheTTL = errors.New("cache TTL must not be negative")
+
+// CacheTTL caches the admin VDCs and storage profiles retrieved by the client for ttl, keyed by
+// their IDs, so that calculating several metrics for the same VDC does not retrieve them again.
+// Concurrent retrievals of the same object are coalesced into a single request. If not set, or
+// set to 0, nothing is cached.
+func CacheTTL(ttl time.Duration) Option {
+	return func(opts *Options) {
+		opts.CacheTTL = ttl
+	}
+}
+
+// CacheStats are the statistics of a client's cache.
+type CacheStats struct {
+	// Hits is the number of lookups answered from the cache.
+	Hits uint64
+	// Misses is the number of lookups that retrieved the object from vCloud, or waited for a
+	// concurrent lookup of the same object to do so.
+	Misses uint64
+	// Entries is the number of objects in the cache that have not expired.
+	Entries int
+}
+
+// cacheEntry is an object held by a cache.
+type cacheEntry struct {
+	value   any
+	expires time.Time
+}
+
+// cache holds objects retrieved from vCloud by their IDs.
+type cache struct {
+	ttl        time.Duration
+	mu         sync.Mutex
+	entries    map[string]cacheEntry
+	generation uint64
+	group      singleflight.Group
+	hits       atomic.Uint64
+	misses     atomic.Uint64
+}
+
+// newCache creates a cache holding objects for ttl, or returns nil if ttl is 0.
+func newCache(ttl time.Duration) *cache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &cache{ttl: ttl, entries: make(map[string]cacheEntry)}
+}
+
+// lookup returns the object cached with id, if it has not expired, and the generation of the
+// cache, which changes when the cache is invalidated.
+func (c *cache) lookup(id string) (any, uint64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[id]
+	if ok && time.Now().After(entry.expires) {
+		delete(c.entries, id)
+		ok = false
+	}
+	return entry.value, c.generation, ok
+}
+
+// store caches value with id, unless the cache was invalidated since generation.
+func (c *cache) store(id string, value any, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.generation != generation {
+		return
+	}
+	c.entries[id] = cacheEntry{value: value, expires: time.Now().Add(c.ttl)}
+}
+
+// invalidate removes the objects cached with ids, or all objects if ids is empty.
+func (c *cache) invalidate(ids ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if len(ids) == 0 {
+		clear(c.entries)
+		return
+	}
+	for _, id := range ids {
+		delete(c.entries, id)
+	}
+}
+
+// stats returns the statistics of the cache.
+func (c *cache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	entries := 0
+	for _, entry := range c.entries {
+		if now.Before(entry.expires) {
+			entries++
+		}
+	}
+	return CacheStats{Hits: c.hits.Load(), Misses: c.misses.Load(), Entries: entries}
+}
+
+// cached returns the object with id from the client's cache or, if it is not cached, retrieves it
+// with fetch and caches it. Concurrent retrievals of the same object share a single call to fetch,
+// which is not aborted if the ctx of the caller that started it is cancelled, so that it completes
+// for the other callers. If the client has no cache, fetch is called directly.
+func cached[T any](ctx context.Context, client *Client, id string, fetch func(ctx context.Context) (T, error)) (T, error) {
+	if client == nil || client.cache == nil {
+		return fetch(ctx)
+	}
+	var zero T
+	c := client.cache
+	value, generation, ok := c.lookup(id)
+	if ok {
+		c.hits.Add(1)
+		return value.(T), nil
+	}
+	key := id + "@" + strconv.FormatUint(generation, 10)
+	done := c.group.DoChan(key, func() (any, error) {
+		value, err := fetch(context.WithoutCancel(ctx))
+		if err != nil {
+			return nil, err
+		}
+		c.store(id, value, generation)
+		return value, nil
+	})
+	// The miss is counted once the caller is waiting on the retrieval, so that every counted
+	// miss shares a retrieval still in flight.
+	c.misses.Add(1)
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-done:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		return res.Val.(T), nil
+	}
+}
+
+// InvalidateCache removes the objects with ids from the client's cache, or all objects if no IDs
+// are given, so that they are retrieved from vCloud again. Retrievals in flight when the cache is
+// invalidated do not populate it.
+func (client *Client) InvalidateCache(ids ...string) {
+	if client == nil || client.cache == nil {
+		return
+	}
+	client.cache.invalidate(ids...)
+}
+
+// CacheStats returns the statistics of the client's cache, which are all zero if the client does
+// not cache objects.
+func (client *Client) CacheStats() CacheStats {
+	if client == nil || client.cache == nil {
+		return CacheStats{}
+	}
+	return client.cache.stats()
+}
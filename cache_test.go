@@ -0,0 +1,142 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_cached(t *testing.T) {
+	t.Run("hit and miss", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}, cache: newCache(time.Minute)}
+		var fetches atomic.Int64
+		fetch := func(context.Context) (string, error) {
+			fetches.Add(1)
+			return "vdc", nil
+		}
+		for i := 0; i < 3; i++ {
+			value, err := cached(context.Background(), client, "vdc-1", fetch)
+			require.NoError(t, err)
+			assert.Equal(t, "vdc", value)
+		}
+		assert.Equal(t, int64(1), fetches.Load())
+		assert.Equal(t, CacheStats{Hits: 2, Misses: 1, Entries: 1}, client.CacheStats())
+	})
+	t.Run("expiry", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}, cache: newCache(20 * time.Millisecond)}
+		var fetches atomic.Int64
+		fetch := func(context.Context) (int64, error) {
+			return fetches.Add(1), nil
+		}
+		first, err := cached(context.Background(), client, "vdc-1", fetch)
+		require.NoError(t, err)
+		time.Sleep(40 * time.Millisecond)
+		assert.Equal(t, 0, client.CacheStats().Entries)
+		second, err := cached(context.Background(), client, "vdc-1", fetch)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), first)
+		assert.Equal(t, int64(2), second)
+	})
+	t.Run("errors", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}, cache: newCache(time.Minute)}
+		errFailed := errors.New("failed")
+		_, err := cached(context.Background(), client, "vdc-1", func(context.Context) (string, error) {
+			return "", errFailed
+		})
+		assert.ErrorIs(t, err, errFailed)
+		assert.Equal(t, 0, client.CacheStats().Entries)
+	})
+	t.Run("invalidate", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}, cache: newCache(time.Minute)}
+		fetch := func(context.Context) (string, error) { return "value", nil }
+		for _, id := range []string{"vdc-1", "vdc-2", "sp-1"} {
+			_, err := cached(context.Background(), client, id, fetch)
+			require.NoError(t, err)
+		}
+		client.InvalidateCache("vdc-1")
+		assert.Equal(t, 2, client.CacheStats().Entries)
+		client.InvalidateCache()
+		assert.Equal(t, 0, client.CacheStats().Entries)
+	})
+	t.Run("invalidate in flight", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}, cache: newCache(time.Minute)}
+		started := make(chan struct{})
+		release := make(chan struct{})
+		go func() {
+			<-started
+			client.InvalidateCache()
+			close(release)
+		}()
+		_, err := cached(context.Background(), client, "vdc-1", func(context.Context) (string, error) {
+			close(started)
+			<-release
+			return "stale", nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 0, client.CacheStats().Entries)
+	})
+	t.Run("coalesce", func(t *testing.T) {
+		t.Parallel()
+		const callers = 8
+		client := &Client{opts: &Options{}, cache: newCache(time.Minute)}
+		var fetches atomic.Int64
+		release := make(chan struct{})
+		cancelled, cancel := context.WithCancel(context.Background())
+		fetch := func(context.Context) (string, error) {
+			fetches.Add(1)
+			<-release
+			return "vdc", nil
+		}
+		cancelledErr := make(chan error, 1)
+		go func() {
+			_, err := cached(cancelled, client, "vdc-1", fetch)
+			cancelledErr <- err
+		}()
+		var wg sync.WaitGroup
+		for i := 1; i < callers; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				value, err := cached(context.Background(), client, "vdc-1", fetch)
+				assert.NoError(t, err)
+				assert.Equal(t, "vdc", value)
+			}()
+		}
+		// Hold the retrieval until every caller is waiting on it, then cancel one of them.
+		require.Eventually(t, func() bool { return client.CacheStats().Misses == callers }, time.Second, time.Millisecond)
+		cancel()
+		assert.ErrorIs(t, <-cancelledErr, context.Canceled)
+		close(release)
+		wg.Wait()
+		assert.Equal(t, uint64(callers), client.CacheStats().Misses)
+		assert.Equal(t, int64(1), fetches.Load())
+		assert.Equal(t, 1, client.CacheStats().Entries)
+	})
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}}
+		var fetches atomic.Int64
+		fetch := func(context.Context) (string, error) {
+			fetches.Add(1)
+			return "vdc", nil
+		}
+		for i := 0; i < 2; i++ {
+			_, err := cached(context.Background(), client, "vdc-1", fetch)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, int64(2), fetches.Load())
+		assert.Equal(t, CacheStats{}, client.CacheStats())
+		client.InvalidateCache()
+	})
+}
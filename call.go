@@ -0,0 +1,89 @@
+package vcdusage
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// callResult holds the return values of a govcd operation executed by call.
+type callResult[T any] struct {
+	value T
+	err   error
+}
+
+// call executes fn, a blocking govcd operation, and returns as soon as either fn completes or ctx
+// is cancelled or its deadline is exceeded. govcd does not accept a context, so a cancelled
+// operation is abandoned rather than interrupted; its result is discarded when it completes. fn is
+// retried according to the client's retry policy, and once more if the client's session expired.
+// name identifies the operation and attrs the objects it operates on when the operation is logged.
+// Errors returned by fn are classified, so that they match ErrUnauthorized, ErrForbidden, and
+// ErrTransient where applicable.
+func call[T any](ctx context.Context, client *Client, name string, fn func() (T, error), attrs ...slog.Attr) (T, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	attrs = slices.Clip(append([]slog.Attr{slog.String("operation", name)}, attrs...))
+	tel := client.tel()
+	ctx, span := tel.tracer.Start(ctx, "govcd."+name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(spanAttrs(attrs)...))
+	started := time.Now()
+	measure := func(err error) {
+		op := metric.WithAttributes(attribute.String("vcd.operation", name))
+		tel.duration.Record(ctx, time.Since(started).Seconds(), op)
+		if err != nil {
+			tel.errors.Add(ctx, 1, op)
+		}
+		endSpan(span, err)
+	}
+	done := make(chan callResult[T], 1)
+	go func() {
+		value, err := retry(ctx, client, attrs, func() (T, error) {
+			return renew(client, fn)
+		})
+		done <- callResult[T]{value: value, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		client.log(ctx, "vCloud operation abandoned", append(attrs, errAttr(ctx.Err()))...)
+		measure(ctx.Err())
+		return zero, ctx.Err()
+	case res := <-done:
+		res.err = client.classify(res.err)
+		client.log(ctx, "vCloud operation completed", append(attrs, slog.Duration("duration", time.Since(started)), errAttr(res.err))...)
+		measure(res.err)
+		return res.value, res.err
+	}
+}
+
+// renew executes fn and, if vCloud rejects it because the client's session has expired,
+// re-authenticates the client and executes fn once more.
+func renew[T any](client *Client, fn func() (T, error)) (T, error) {
+	if client == nil {
+		return fn()
+	}
+	var zero T
+	gen, err := client.session.acquire()
+	if err != nil {
+		return zero, err
+	}
+	value, err := fn()
+	client.session.release()
+	if err == nil || !isUnauthorized(err) || !client.canReauthenticate() {
+		return value, err
+	}
+	if rerr := client.reauthenticate(gen); rerr != nil {
+		return zero, errorx.Decorate(rerr, "failed to renew expired vCloud session")
+	}
+	if _, err := client.session.acquire(); err != nil {
+		return zero, err
+	}
+	defer client.session.release()
+	return fn()
+}
@@ -1,25 +1,69 @@
 package vcdusage
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/joomcode/errorx"
 	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
 )
 
+// DefaultConcurrency is the maximum number of VDCs queried at once by VDCs methods when the
+// Concurrency option is not set.
+const DefaultConcurrency = 8
+
 type Client struct {
-	VCD *govcd.VCDClient
+	VCD       *govcd.VCDClient
+	opts      *Options
+	session   session
+	telemetry *telemetry
+	cache     *cache
 }
 
 type Options struct {
-	Insecure bool
-	Org      string
-	Username string
-	Password string
-	URL      *url.URL
+	Insecure           bool
+	Org                string
+	Username           string
+	Password           string
+	APIToken           string
+	BearerToken        string
+	ServiceAccountFile string
+	URL                *url.URL
+	Concurrency        int
+	OnReauthenticate   func(err error)
+	CACerts            [][]byte
+	CertFingerprints   []string
+	ClientCert         *tls.Certificate
+	MinTLSVersion      uint16
+	Timeout            time.Duration
+	Proxy              *url.URL
+	MaxIdleConns       int
+	UserAgent          string
+	Transport          http.RoundTripper
+	Middleware         []func(http.RoundTripper) http.RoundTripper
+	Retry              *RetryPolicy
+	RateLimiter        *rate.Limiter
+	APIVersion         string
+	Logger             *slog.Logger
+	LogLevel           slog.Leveler
+	TracerProvider     trace.TracerProvider
+	MeterProvider      metric.MeterProvider
+	CacheTTL           time.Duration
+	// err holds an error encountered while applying an option, returned by Validate.
+	err error
+	// loadedAuth is set when the authentication options were loaded from the environment or a
+	// configuration file, so that authentication options set explicitly replace them.
+	loadedAuth bool
 }
 
 // ErrCfgNoUsername indicates an authentication username was not provided.
@@ -31,17 +75,65 @@ var ErrCfgNoPassword = errors.New("password required")
 // ErrCfgNoURL indicates a URL was not provided.
 var ErrCfgNoURL = errors.New("URL required")
 
-// Validate ensures required parameters are set.
+// ErrCfgConcurrency indicates the concurrency limit is less than one.
+var ErrCfgConcurrency = errors.New("concurrency must be at least 1")
+
+// ErrCfgAuthConflict indicates more than one authentication method was configured.
+var ErrCfgAuthConflict = errors.New("only one of username/password, API token, bearer token, or service account may be used")
+
+// Validate ensures required parameters are set. A username and password are only required when no
+// API token, bearer token, or service account is set.
 func (opts *Options) Validate() error {
-	if opts.Username == "" {
-		return ErrCfgNoUsername
+	if opts.err != nil {
+		return opts.err
 	}
-	if opts.Password == "" {
-		return ErrCfgNoPassword
+	if opts.authMethods() > 1 {
+		return ErrCfgAuthConflict
+	}
+	if opts.usesBasicAuth() {
+		if opts.Username == "" {
+			return ErrCfgNoUsername
+		}
+		if opts.Password == "" {
+			return ErrCfgNoPassword
+		}
 	}
 	if opts.URL == nil {
 		return ErrCfgNoURL
 	}
+	if opts.Concurrency < 1 {
+		return ErrCfgConcurrency
+	}
+	for _, bundle := range opts.CACerts {
+		if !x509.NewCertPool().AppendCertsFromPEM(bundle) {
+			return ErrCfgCACert
+		}
+	}
+	for _, fingerprint := range opts.CertFingerprints {
+		if _, err := parseFingerprint(fingerprint); err != nil {
+			return err
+		}
+	}
+	if opts.MinTLSVersion != 0 && !validTLSVersion(opts.MinTLSVersion) {
+		return ErrCfgTLSVersion
+	}
+	if opts.Transport != nil && opts.usesDefaultTransport() {
+		return ErrCfgTransportConflict
+	}
+	if opts.RateLimiter != nil && opts.RateLimiter.Limit() != rate.Inf && (opts.RateLimiter.Limit() <= 0 || opts.RateLimiter.Burst() < 1) {
+		return ErrCfgRateLimit
+	}
+	if opts.APIVersion != "" && !apiVersionPattern.MatchString(opts.APIVersion) {
+		return ErrCfgAPIVersion
+	}
+	if opts.CacheTTL < 0 {
+		return ErrCfgCacheTTL
+	}
+	if opts.Retry != nil {
+		if err := opts.Retry.Validate(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -62,20 +154,106 @@ func Org(org string) Option {
 	}
 }
 
-// Username sets the authentication username. This option is required.
+// authMethods returns the number of authentication methods that are set.
+func (opts *Options) authMethods() int {
+	methods := 0
+	for _, set := range []bool{
+		opts.Username != "" || opts.Password != "",
+		opts.APIToken != "",
+		opts.BearerToken != "",
+		opts.ServiceAccountFile != "",
+	} {
+		if set {
+			methods++
+		}
+	}
+	return methods
+}
+
+// replaceLoadedAuth clears authentication options loaded from the environment or a configuration
+// file before an authentication option is set explicitly. If keepBasic is set, a loaded username
+// and password are kept, so that an explicit username can be combined with a loaded password.
+func (opts *Options) replaceLoadedAuth(keepBasic bool) {
+	if !opts.loadedAuth {
+		return
+	}
+	if !keepBasic {
+		opts.Username = ""
+		opts.Password = ""
+	}
+	opts.APIToken = ""
+	opts.BearerToken = ""
+	opts.ServiceAccountFile = ""
+	opts.loadedAuth = false
+}
+
+// usesBasicAuth determines if the client authenticates with a username and password.
+func (opts *Options) usesBasicAuth() bool {
+	return opts.APIToken == "" && opts.BearerToken == "" && opts.ServiceAccountFile == ""
+}
+
+// authenticate authenticates vcd using the configured authentication method.
+func (opts *Options) authenticate(vcd *govcd.VCDClient) error {
+	switch {
+	case opts.APIToken != "":
+		_, err := vcd.SetApiToken(opts.Org, opts.APIToken)
+		return err
+	case opts.BearerToken != "":
+		return vcd.SetToken(opts.Org, govcd.BearerTokenHeader, opts.BearerToken)
+	case opts.ServiceAccountFile != "":
+		return vcd.SetServiceAccountApiToken(opts.Org, opts.ServiceAccountFile)
+	default:
+		return vcd.Authenticate(opts.Username, opts.Password, opts.Org)
+	}
+}
+
+// Username sets the authentication username. This option is required unless an API token, bearer
+// token, or service account is used.
 func Username(u string) Option {
 	return func(opts *Options) {
+		opts.replaceLoadedAuth(true)
 		opts.Username = u
 	}
 }
 
-// Password sets the authentication password. This option is required.
+// Password sets the authentication password. This option is required unless an API token, bearer
+// token, or service account is used.
 func Password(p string) Option {
 	return func(opts *Options) {
+		opts.replaceLoadedAuth(true)
 		opts.Password = p
 	}
 }
 
+// APIToken authenticates with a vCloud API token (a refresh token), which is exchanged for a bearer
+// token when the client is created. The token must belong to the organization set by Org.
+func APIToken(token string) Option {
+	return func(opts *Options) {
+		opts.replaceLoadedAuth(false)
+		opts.APIToken = token
+	}
+}
+
+// BearerToken authenticates with an existing vCloud bearer token. The token must belong to the
+// organization set by Org.
+func BearerToken(token string) Option {
+	return func(opts *Options) {
+		opts.replaceLoadedAuth(false)
+		opts.BearerToken = token
+	}
+}
+
+// ServiceAccount authenticates as a vCloud service account using the API token stored in file.
+// vCloud rotates service account tokens on use, so the file must be writable; it is updated with
+// the new token each time the client authenticates. The service account must belong to the
+// organization set by Org.
+func ServiceAccount(file string) Option {
+	return func(opts *Options) {
+		opts.replaceLoadedAuth(false)
+		opts.ServiceAccountFile = file
+	}
+}
+
 // URL sets the vCloud base URL. This option is required. See ParseURL helper.
 func URL(u *url.URL) Option {
 	return func(opts *Options) {
@@ -83,6 +261,24 @@ func URL(u *url.URL) Option {
 	}
 }
 
+// Concurrency sets the maximum number of VDCs queried at once by VDCs methods. If not set,
+// DefaultConcurrency will be used.
+func Concurrency(n int) Option {
+	return func(opts *Options) {
+		opts.Concurrency = n
+	}
+}
+
+// OnReauthenticate sets a function called each time the client re-authenticates with vCloud after
+// its session has expired. err is nil if re-authentication succeeded. fn is called once the new
+// session is in use, so it may use the client, but the request that found the session expired is
+// not retried until fn returns.
+func OnReauthenticate(fn func(err error)) Option {
+	return func(opts *Options) {
+		opts.OnReauthenticate = fn
+	}
+}
+
 // ParseURL parses a vCloud URL from a string to a *url.URL, sets the appropriate URI schema, and
 // sets the correct path.
 func ParseURL(u string) (*url.URL, error) {
@@ -101,11 +297,13 @@ func ParseURL(u string) (*url.URL, error) {
 	return pu, nil
 }
 
-// Create a new VCD Usage client.
+// Create a new VCD Usage client. If vCloud rejects the client's credentials, the error matches
+// ErrUnauthorized, and if vCloud cannot be reached, the error matches ErrTransient.
 func New(options ...Option) (*Client, error) {
 	opts := &Options{
-		Insecure: false,
-		Org:      "system",
+		Insecure:    false,
+		Org:         "system",
+		Concurrency: DefaultConcurrency,
 	}
 	for _, setter := range options {
 		setter(opts)
@@ -115,14 +313,76 @@ func New(options ...Option) (*Client, error) {
 		err = errorx.Decorate(err, "option validation failed")
 		return nil, err
 	}
+	tel, err := opts.telemetry()
+	if err != nil {
+		return nil, err
+	}
 	vcd := govcd.NewVCDClient(*opts.URL, opts.Insecure)
-	err = vcd.Authenticate(opts.Username, opts.Password, opts.Org)
+	err = opts.configureHTTP(vcd)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to configure HTTP client")
+		return nil, err
+	}
+	err = opts.negotiateAPIVersion(vcd)
 	if err != nil {
-		err = errorx.Decorate(err, "failed to authenticate with vCloud host '%s'", opts.URL.String())
+		err = errorx.Decorate(opts.classify(err), "failed to negotiate API version with vCloud host '%s'", opts.URL.String())
+		return nil, err
+	}
+	err = opts.authenticate(vcd)
+	if err != nil {
+		err = errorx.Decorate(opts.classify(err), "failed to authenticate with vCloud host '%s'", opts.URL.String())
 		return nil, err
 	}
 	client := &Client{
-		VCD: vcd,
+		VCD:       vcd,
+		opts:      opts,
+		telemetry: tel,
+		cache:     newCache(opts.CacheTTL),
 	}
 	return client, nil
 }
+
+// concurrency returns the maximum number of concurrent operations allowed by the client.
+func (client *Client) concurrency() int {
+	if client == nil || client.opts == nil || client.opts.Concurrency < 1 {
+		return DefaultConcurrency
+	}
+	return client.opts.Concurrency
+}
+
+// Close logs out of vCloud, ending the client's session, and releases its idle connections. Once
+// Close is called, every method that communicates with vCloud returns ErrClientClosed. Closing a
+// closed client has no effect.
+//
+// Close waits for requests in flight to complete, including requests abandoned because their
+// context was cancelled, which govcd cannot interrupt. Close may therefore block for up to the
+// request timeout; see Timeout.
+//
+// A bearer token set with BearerToken belongs to the caller and may be used elsewhere, so the
+// session is not logged out; only idle connections are released.
+func (client *Client) Close() error {
+	if !client.session.closed.CompareAndSwap(false, true) {
+		return nil
+	}
+	client.session.mu.Lock()
+	defer client.session.mu.Unlock()
+	client.InvalidateCache()
+	if client.VCD == nil {
+		return nil
+	}
+	defer client.VCD.Client.Http.CloseIdleConnections()
+	if client.opts != nil && client.opts.BearerToken != "" {
+		return nil
+	}
+	err := client.VCD.Disconnect()
+	if err != nil {
+		err = errorx.Decorate(err, "failed to log out of vCloud")
+		return err
+	}
+	return nil
+}
+
+// Logout is the same as Close.
+func (client *Client) Logout() error {
+	return client.Close()
+}
@@ -0,0 +1,47 @@
+package vcdusage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_close(t *testing.T) {
+	t.Run("in flight", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}}
+		// Hold the session as a request abandoned by its context would.
+		_, err := client.session.acquire()
+		require.NoError(t, err)
+		closed := make(chan error)
+		go func() {
+			closed <- client.Close()
+		}()
+		require.Eventually(t, client.session.closed.Load, time.Second, time.Millisecond)
+		_, err = call(context.Background(), client, "Test", func() (int, error) {
+			return 1, nil
+		})
+		assert.ErrorIs(t, err, ErrClientClosed)
+		assert.NoError(t, client.Close())
+		select {
+		case <-closed:
+			t.Fatal("closed before the request in flight completed")
+		default:
+		}
+		client.session.release()
+		assert.NoError(t, <-closed)
+	})
+	t.Run("bearer token", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 1)
+		client := srv.client(t)
+		client.opts.BearerToken = "token"
+		client.VCD.Client.VCDToken = "token"
+		client.VCD.Client.VCDAuthHeader = "Authorization"
+		// The client has no session to log out of, so logging out would fail.
+		assert.NoError(t, client.Close())
+	})
+}
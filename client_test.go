@@ -1,25 +1,60 @@
 package vcdusage_test
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/http"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.stellar.af/go-vcdusage"
+	"golang.org/x/time/rate"
 )
 
 func Test_New(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	assert.NotEmpty(t, client.APIVersion())
+}
+
+func Test_NewUnauthorized(t *testing.T) {
 	u, err := vcdusage.ParseURL(Env.URL)
 	require.NoError(t, err)
 	_, err = vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password+"-invalid"),
+	)
+	assert.ErrorIs(t, err, vcdusage.ErrUnauthorized)
+	assert.NotErrorIs(t, err, vcdusage.ErrTransient)
+}
+
+func Test_Close(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
 		vcdusage.Insecure(),
 		vcdusage.URL(u),
 		vcdusage.Username(Env.Username),
 		vcdusage.Password(Env.Password),
 	)
 	require.NoError(t, err)
+	require.NoError(t, client.Close())
+	require.NoError(t, client.Close())
+	_, err = client.Org(Env.OrgID)
+	assert.ErrorIs(t, err, vcdusage.ErrClientClosed)
 }
 
 func Test_ParseURL(t *testing.T) {
@@ -43,3 +78,51 @@ func Test_ParseURL(t *testing.T) {
 		})
 	}
 }
+
+func Test_OptionsValidate(t *testing.T) {
+	u, err := vcdusage.ParseURL("vcd.example.com")
+	require.NoError(t, err)
+	cases := []struct {
+		name string
+		opts vcdusage.Options
+		err  error
+	}{
+		{"basic auth", vcdusage.Options{URL: u, Username: "user", Password: "pass", Concurrency: 1}, nil},
+		{"no username", vcdusage.Options{URL: u, Password: "pass", Concurrency: 1}, vcdusage.ErrCfgNoUsername},
+		{"no password", vcdusage.Options{URL: u, Username: "user", Concurrency: 1}, vcdusage.ErrCfgNoPassword},
+		{"API token", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1}, nil},
+		{"bearer token", vcdusage.Options{URL: u, BearerToken: "token", Concurrency: 1}, nil},
+		{"service account", vcdusage.Options{URL: u, ServiceAccountFile: "token.json", Concurrency: 1}, nil},
+		{"conflict", vcdusage.Options{URL: u, Username: "user", APIToken: "token", Concurrency: 1}, vcdusage.ErrCfgAuthConflict},
+		{"no URL", vcdusage.Options{APIToken: "token", Concurrency: 1}, vcdusage.ErrCfgNoURL},
+		{"no concurrency", vcdusage.Options{URL: u, APIToken: "token"}, vcdusage.ErrCfgConcurrency},
+		{"fingerprint", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, CertFingerprints: []string{strings.Repeat("ab:", 31) + "ab"}}, nil},
+		{"bad fingerprint", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, CertFingerprints: []string{"abcd"}}, vcdusage.ErrCfgFingerprint},
+		{"bad CA bundle", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, CACerts: [][]byte{[]byte("bundle")}}, vcdusage.ErrCfgCACert},
+		{"TLS version", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, MinTLSVersion: tls.VersionTLS13}, nil},
+		{"transport", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, Transport: http.DefaultTransport}, nil},
+		{"transport conflict", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, Transport: http.DefaultTransport, Insecure: true}, vcdusage.ErrCfgTransportConflict},
+		{"rate limit", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, RateLimiter: rate.NewLimiter(10, 5)}, nil},
+		{"bad rate limit", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, RateLimiter: rate.NewLimiter(10, 0)}, vcdusage.ErrCfgRateLimit},
+		{"zero rate limit", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, RateLimiter: rate.NewLimiter(0, 5)}, vcdusage.ErrCfgRateLimit},
+		{"negative rate limit", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, RateLimiter: rate.NewLimiter(-1, 5)}, vcdusage.ErrCfgRateLimit},
+		{"unlimited rate", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, RateLimiter: rate.NewLimiter(rate.Inf, 0)}, nil},
+		{"API version", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, APIVersion: "37.0"}, nil},
+		{"bad API version", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, APIVersion: "37"}, vcdusage.ErrCfgAPIVersion},
+		{"bad TLS version", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, MinTLSVersion: 1}, vcdusage.ErrCfgTLSVersion},
+		{"cache", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, CacheTTL: time.Minute}, nil},
+		{"bad cache TTL", vcdusage.Options{URL: u, APIToken: "token", Concurrency: 1, CacheTTL: -time.Minute}, vcdusage.ErrCfgCacheTTL},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			err := c.opts.Validate()
+			if c.err == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, c.err)
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package vcdusage
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"sigs.k8s.io/yaml"
+)
+
+// config holds options loaded from the environment or a configuration file.
+type config struct {
+	URL                string `json:"url"`
+	Org                string `json:"org"`
+	Username           string `json:"username"`
+	Password           string `json:"password"`
+	PasswordFile       string `json:"passwordFile"`
+	APIToken           string `json:"apiToken"`
+	ServiceAccountFile string `json:"serviceAccountFile"`
+	Insecure           bool   `json:"insecure"`
+	Concurrency        int    `json:"concurrency"`
+	url                *url.URL
+}
+
+// resolve parses the configured URL and, if no password is set, reads the password from the
+// password file.
+func (cfg *config) resolve() error {
+	if cfg.URL != "" {
+		u, err := ParseURL(cfg.URL)
+		if err != nil {
+			return err
+		}
+		cfg.url = u
+	}
+	if cfg.Password == "" && cfg.PasswordFile != "" {
+		password, err := readSecret(cfg.PasswordFile)
+		if err != nil {
+			return err
+		}
+		cfg.Password = password
+	}
+	return nil
+}
+
+// apply sets each option that is set in cfg. cfg must be resolved first.
+func (cfg *config) apply(opts *Options) {
+	if cfg.url != nil {
+		opts.URL = cfg.url
+	}
+	if cfg.Org != "" {
+		opts.Org = cfg.Org
+	}
+	cfg.applyAuth(opts)
+	if cfg.Insecure {
+		opts.Insecure = true
+	}
+	if cfg.Concurrency != 0 {
+		opts.Concurrency = cfg.Concurrency
+	}
+}
+
+// applyAuth sets each authentication option that is set in cfg, unless an authentication method
+// has already been set explicitly, which takes precedence.
+func (cfg *config) applyAuth(opts *Options) {
+	if opts.authMethods() != 0 && !opts.loadedAuth {
+		return
+	}
+	if cfg.Username != "" {
+		opts.Username = cfg.Username
+		opts.loadedAuth = true
+	}
+	if cfg.Password != "" {
+		opts.Password = cfg.Password
+		opts.loadedAuth = true
+	}
+	if cfg.APIToken != "" {
+		opts.APIToken = cfg.APIToken
+		opts.loadedAuth = true
+	}
+	if cfg.ServiceAccountFile != "" {
+		opts.ServiceAccountFile = cfg.ServiceAccountFile
+		opts.loadedAuth = true
+	}
+}
+
+// readSecret reads a secret from file, such as a mounted Kubernetes secret, ignoring surrounding
+// whitespace.
+func readSecret(file string) (string, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to read secret file '%s'", file)
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// FromEnvironment sets options from the following environment variables. Variables that are not
+// set are ignored, and options set after FromEnvironment take precedence. Credentials are ignored
+// if an authentication method is set explicitly, and are replaced by any authentication method
+// set explicitly afterwards.
+//
+//   - VCD_URL: vCloud URL, parsed with ParseURL
+//   - VCD_ORG: organization name used when authenticating
+//   - VCD_USERNAME: authentication username
+//   - VCD_PASSWORD: authentication password
+//   - VCD_PASSWORD_FILE: file containing the authentication password, used if VCD_PASSWORD is not set
+//   - VCD_API_TOKEN: API token
+//   - VCD_SERVICE_ACCOUNT_FILE: service account token file
+//   - VCD_INSECURE: disables SSL certificate validation if true
+//
+// If the environment is invalid, the error is returned when the options are validated.
+func FromEnvironment() Option {
+	return func(opts *Options) {
+		cfg := &config{
+			URL:                os.Getenv("VCD_URL"),
+			Org:                os.Getenv("VCD_ORG"),
+			Username:           os.Getenv("VCD_USERNAME"),
+			Password:           os.Getenv("VCD_PASSWORD"),
+			PasswordFile:       os.Getenv("VCD_PASSWORD_FILE"),
+			APIToken:           os.Getenv("VCD_API_TOKEN"),
+			ServiceAccountFile: os.Getenv("VCD_SERVICE_ACCOUNT_FILE"),
+		}
+		if insecure := os.Getenv("VCD_INSECURE"); insecure != "" {
+			value, err := strconv.ParseBool(insecure)
+			if err != nil {
+				opts.err = errorx.Decorate(err, "invalid value for VCD_INSECURE")
+				return
+			}
+			cfg.Insecure = value
+		}
+		if err := cfg.resolve(); err != nil {
+			opts.err = errorx.Decorate(err, "failed to load options from environment")
+			return
+		}
+		cfg.apply(opts)
+	}
+}
+
+// LoadConfig reads options from a YAML or JSON configuration file and returns them as a single
+// Option. The following keys are supported; keys that are not set are ignored.
+//
+//	url: https://vcd.example.com
+//	org: system
+//	username: user
+//	password: pass
+//	passwordFile: /var/run/secrets/vcd/password
+//	apiToken: token
+//	serviceAccountFile: /var/run/secrets/vcd/service-account.json
+//	insecure: false
+//	concurrency: 8
+//
+// passwordFile is used if password is not set. Relative paths to files are relative to the
+// directory of the configuration file. As with FromEnvironment, credentials are ignored if an
+// authentication method is set explicitly.
+func LoadConfig(path string) (Option, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to read config file '%s'", path)
+		return nil, err
+	}
+	cfg := &config{}
+	err = yaml.UnmarshalStrict(data, cfg)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to parse config file '%s'", path)
+		return nil, err
+	}
+	dir := filepath.Dir(path)
+	for _, file := range []*string{&cfg.PasswordFile, &cfg.ServiceAccountFile} {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(dir, *file)
+		}
+	}
+	err = cfg.resolve()
+	if err != nil {
+		err = errorx.Decorate(err, "failed to load config file '%s'", path)
+		return nil, err
+	}
+	return cfg.apply, nil
+}
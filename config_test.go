@@ -0,0 +1,130 @@
+package vcdusage_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.stellar.af/go-vcdusage"
+)
+
+func Test_FromEnvironment(t *testing.T) {
+	t.Run("basic auth", func(t *testing.T) {
+		t.Setenv("VCD_URL", "vcd.example.com")
+		t.Setenv("VCD_USERNAME", "user")
+		t.Setenv("VCD_PASSWORD", "pass")
+		t.Setenv("VCD_ORG", "example")
+		t.Setenv("VCD_INSECURE", "true")
+		opts := &vcdusage.Options{Concurrency: 1}
+		vcdusage.FromEnvironment()(opts)
+		require.NoError(t, opts.Validate())
+		assert.Equal(t, "https://vcd.example.com/api", opts.URL.String())
+		assert.Equal(t, "user", opts.Username)
+		assert.Equal(t, "pass", opts.Password)
+		assert.Equal(t, "example", opts.Org)
+		assert.True(t, opts.Insecure)
+	})
+	t.Run("password file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "password")
+		require.NoError(t, os.WriteFile(file, []byte("secret\n"), 0o600))
+		t.Setenv("VCD_URL", "vcd.example.com")
+		t.Setenv("VCD_USERNAME", "user")
+		t.Setenv("VCD_PASSWORD", "")
+		t.Setenv("VCD_PASSWORD_FILE", file)
+		opts := &vcdusage.Options{Concurrency: 1}
+		vcdusage.FromEnvironment()(opts)
+		require.NoError(t, opts.Validate())
+		assert.Equal(t, "secret", opts.Password)
+	})
+	t.Run("missing password file", func(t *testing.T) {
+		t.Setenv("VCD_URL", "vcd.example.com")
+		t.Setenv("VCD_USERNAME", "user")
+		t.Setenv("VCD_PASSWORD", "")
+		t.Setenv("VCD_PASSWORD_FILE", filepath.Join(t.TempDir(), "password"))
+		opts := &vcdusage.Options{Concurrency: 1}
+		vcdusage.FromEnvironment()(opts)
+		assert.ErrorIs(t, opts.Validate(), os.ErrNotExist)
+	})
+	t.Run("later options take precedence", func(t *testing.T) {
+		t.Setenv("VCD_URL", "vcd.example.com")
+		t.Setenv("VCD_USERNAME", "user")
+		t.Setenv("VCD_PASSWORD", "pass")
+		opts := &vcdusage.Options{Concurrency: 1}
+		vcdusage.FromEnvironment()(opts)
+		vcdusage.Username("other")(opts)
+		assert.Equal(t, "other", opts.Username)
+		assert.Equal(t, "pass", opts.Password)
+	})
+	t.Run("explicit token after environment", func(t *testing.T) {
+		t.Setenv("VCD_URL", "vcd.example.com")
+		t.Setenv("VCD_USERNAME", "user")
+		t.Setenv("VCD_PASSWORD", "pass")
+		opts := &vcdusage.Options{Concurrency: 1}
+		vcdusage.FromEnvironment()(opts)
+		vcdusage.APIToken("token")(opts)
+		require.NoError(t, opts.Validate())
+		assert.Equal(t, "token", opts.APIToken)
+		assert.Empty(t, opts.Username)
+		assert.Empty(t, opts.Password)
+	})
+	t.Run("explicit token before environment", func(t *testing.T) {
+		t.Setenv("VCD_URL", "vcd.example.com")
+		t.Setenv("VCD_USERNAME", "user")
+		t.Setenv("VCD_PASSWORD", "pass")
+		opts := &vcdusage.Options{Concurrency: 1}
+		vcdusage.APIToken("token")(opts)
+		vcdusage.FromEnvironment()(opts)
+		require.NoError(t, opts.Validate())
+		assert.Equal(t, "token", opts.APIToken)
+		assert.Empty(t, opts.Username)
+	})
+	t.Run("explicit conflict", func(t *testing.T) {
+		t.Setenv("VCD_URL", "vcd.example.com")
+		opts := &vcdusage.Options{Concurrency: 1}
+		vcdusage.FromEnvironment()(opts)
+		vcdusage.Username("user")(opts)
+		vcdusage.APIToken("token")(opts)
+		assert.ErrorIs(t, opts.Validate(), vcdusage.ErrCfgAuthConflict)
+	})
+}
+
+func Test_LoadConfig(t *testing.T) {
+	t.Run("yaml", func(t *testing.T) {
+		t.Parallel()
+		dir := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0o600))
+		path := filepath.Join(dir, "vcd.yaml")
+		data := "url: vcd.example.com\nusername: user\npasswordFile: password\nconcurrency: 4\n"
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		option, err := vcdusage.LoadConfig(path)
+		require.NoError(t, err)
+		opts := &vcdusage.Options{Concurrency: 1}
+		option(opts)
+		require.NoError(t, opts.Validate())
+		assert.Equal(t, "https://vcd.example.com/api", opts.URL.String())
+		assert.Equal(t, "secret", opts.Password)
+		assert.Equal(t, 4, opts.Concurrency)
+	})
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "vcd.json")
+		data := `{"url": "vcd.example.com", "apiToken": "token", "org": "example"}`
+		require.NoError(t, os.WriteFile(path, []byte(data), 0o600))
+		option, err := vcdusage.LoadConfig(path)
+		require.NoError(t, err)
+		opts := &vcdusage.Options{Concurrency: 1}
+		option(opts)
+		require.NoError(t, opts.Validate())
+		assert.Equal(t, "token", opts.APIToken)
+		assert.Equal(t, "example", opts.Org)
+	})
+	t.Run("unknown key", func(t *testing.T) {
+		t.Parallel()
+		path := filepath.Join(t.TempDir(), "vcd.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("user: user\n"), 0o600))
+		_, err := vcdusage.LoadConfig(path)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,142 @@
+package vcdusage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// ErrOrgNotFound indicates an organization does not exist or is not visible to the client.
+var ErrOrgNotFound = errors.New("org not found")
+
+// ErrVDCNotFound indicates a VDC does not exist or is not visible to the client.
+var ErrVDCNotFound = errors.New("VDC not found")
+
+// ErrAmbiguousName indicates a name matches more than one object when compared
+// case-insensitively, and none exactly.
+var ErrAmbiguousName = errors.New("name matches more than one object")
+
+// ErrClientClosed indicates a client was used after it was closed.
+var ErrClientClosed = errors.New("client is closed")
+
+// ErrUnauthorized indicates vCloud rejected the client's credentials or session.
+var ErrUnauthorized = errors.New("not authorized by vCloud")
+
+// ErrForbidden indicates vCloud denied the client access to an object or operation.
+var ErrForbidden = errors.New("access denied by vCloud")
+
+// ErrTransient indicates a vCloud operation failed with an error that may not recur, such as a
+// network error or a response with a retryable status code. If the client retries operations,
+// the error persisted after the last attempt.
+var ErrTransient = errors.New("transient vCloud error")
+
+// StatusError indicates vCloud responded to a request with an error status. It matches
+// ErrUnauthorized and ErrForbidden with errors.Is for the corresponding status codes.
+type StatusError struct {
+	// StatusCode is the HTTP status code of the vCloud response.
+	StatusCode int
+	// Err is the error returned by govcd.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error returned by govcd.
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// Is determines if the status code of the vCloud response corresponds to target.
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// notFound wraps err with sentinel if err is a govcd not-found error.
+func notFound(err error, sentinel error) error {
+	if govcd.ContainsNotFound(err) {
+		return fmt.Errorf("%w: %w", sentinel, err)
+	}
+	return err
+}
+
+// statusPattern matches the HTTP status code in the text of a govcd error: an API error, an HTTP
+// status, or the status of a response whose error body could not be parsed. Other numbers in the
+// text, such as in the names of objects, are not status codes.
+var statusPattern = regexp.MustCompile(`(?:API Error: |HTTP |StatusCode:)(\d{3})\b`)
+
+// statusCode returns the HTTP status code of the vCloud response that caused err, or 0 if err was
+// not caused by an error response. govcd flattens most errors to strings, so the status code is
+// parsed from the error text when it is not available from a typed error.
+func statusCode(err error) int {
+	var apiErr *types.Error
+	if errors.As(err, &apiErr) && apiErr.MajorErrorCode != 0 {
+		return apiErr.MajorErrorCode
+	}
+	match := statusPattern.FindStringSubmatch(err.Error())
+	if match == nil {
+		return 0
+	}
+	code, _ := strconv.Atoi(match[1])
+	return code
+}
+
+// isUnauthorized determines if err was caused by vCloud rejecting the client's session, e.g.
+// because it has expired.
+func isUnauthorized(err error) bool {
+	return statusCode(err) == http.StatusUnauthorized
+}
+
+// isNotFound determines if err was caused by an object not existing, e.g. because it was deleted
+// after it was listed.
+func isNotFound(err error) bool {
+	return statusCode(err) == http.StatusNotFound || govcd.ContainsNotFound(err)
+}
+
+// classify wraps err, an error from govcd, so that it matches the sentinel errors describing its
+// cause: in a *StatusError if vCloud responded with an error status, and with ErrTransient if the
+// client's retry policy, or the default retry policy if the client does not retry, considers it
+// transient.
+func (opts *Options) classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) || errors.Is(err, ErrTransient) {
+		return err
+	}
+	classified := err
+	if code := statusCode(err); code != 0 {
+		classified = &StatusError{StatusCode: code, Err: err}
+	}
+	policy := DefaultRetryPolicy()
+	if opts != nil && opts.Retry != nil {
+		policy = *opts.Retry
+	}
+	if policy.retryable(err) {
+		classified = fmt.Errorf("%w: %w", ErrTransient, classified)
+	}
+	return classified
+}
+
+// classify wraps err, an error from govcd, so that it matches the sentinel errors describing its
+// cause.
+func (client *Client) classify(err error) error {
+	if client == nil {
+		return (*Options)(nil).classify(err)
+	}
+	return client.opts.classify(err)
+}
@@ -0,0 +1,150 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+func Test_statusCode(t *testing.T) {
+	type caseT struct {
+		name string
+		err  error
+		want int
+	}
+	cases := []caseT{
+		{"typed", fmt.Errorf("error retrieving org: %w", &types.Error{MajorErrorCode: 401}), 401},
+		{"api error", errors.New("error retrieving org: API Error: 401: Unauthorized"), 401},
+		{"http", errors.New("received response HTTP 503"), 503},
+		{"parse error", errors.New("[ParseErr]: error parsing error body (&{Status:503 Service Unavailable StatusCode:503})"), 503},
+		{"status text", errors.New("error retrieving VDC: 403 Forbidden"), 0},
+		{"object name", errors.New("error retrieving edge gateway 'Edge 503 Tier': [ENF] entity not found"), 0},
+		{"none", errors.New("[ENF] entity not found"), 0},
+		{"number", errors.New("found 200 VMs"), 0},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			assert.Equal(t, c.want, statusCode(c.err))
+		})
+	}
+}
+
+func Test_renew(t *testing.T) {
+	t.Run("bearer token", func(t *testing.T) {
+		t.Parallel()
+		called := false
+		client := &Client{opts: &Options{
+			BearerToken:      "token",
+			OnReauthenticate: func(error) { called = true },
+		}}
+		unauthorized := errors.New("API Error: 401: Unauthorized")
+		attempts := 0
+		_, err := renew(client, func() (int, error) {
+			attempts++
+			return 0, unauthorized
+		})
+		assert.ErrorIs(t, err, unauthorized)
+		assert.Equal(t, 1, attempts)
+		assert.False(t, called)
+	})
+	t.Run("stale generation", func(t *testing.T) {
+		t.Parallel()
+		called := false
+		client := &Client{opts: &Options{OnReauthenticate: func(error) { called = true }}}
+		client.session.generation = 2
+		assert.NoError(t, client.reauthenticate(1))
+		assert.False(t, called)
+	})
+	t.Run("callback uses client", func(t *testing.T) {
+		t.Parallel()
+		srv := httptest.NewServer(http.NotFoundHandler())
+		srv.Close()
+		u, err := ParseURL(srv.URL)
+		require.NoError(t, err)
+		client := &Client{VCD: govcd.NewVCDClient(*u, true)}
+		var callbackErr error
+		client.opts = &Options{Username: "user", Password: "pass", OnReauthenticate: func(err error) {
+			_, callbackErr = renew(client, func() (int, error) { return 0, err })
+		}}
+		done := make(chan error, 1)
+		go func() { done <- client.reauthenticate(0) }()
+		select {
+		case err := <-done:
+			assert.Error(t, err)
+			assert.Equal(t, err, callbackErr)
+		case <-time.After(5 * time.Second):
+			t.Fatal("OnReauthenticate could not use the client")
+		}
+	})
+	t.Run("closed", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}}
+		require.NoError(t, client.Close())
+		require.NoError(t, client.Close())
+		called := false
+		_, err := renew(client, func() (int, error) {
+			called = true
+			return 1, nil
+		})
+		assert.ErrorIs(t, err, ErrClientClosed)
+		assert.False(t, called)
+	})
+}
+
+func Test_classify(t *testing.T) {
+	type caseT struct {
+		name         string
+		err          error
+		opts         *Options
+		status       int
+		unauthorized bool
+		forbidden    bool
+		transient    bool
+	}
+	cases := []caseT{
+		{"unauthorized", errors.New("error authorizing: API Error: 401: Unauthorized"), nil, 401, true, false, false},
+		{"forbidden", fmt.Errorf("error retrieving VDC: %w", &types.Error{MajorErrorCode: 403}), nil, 403, false, true, false},
+		{"unavailable", errors.New("received response HTTP 503"), nil, 503, false, false, true},
+		{"internal", errors.New("API Error: 500: Internal Server Error"), nil, 500, false, false, false},
+		{"retried internal", errors.New("API Error: 500: Internal Server Error"), &Options{Retry: &RetryPolicy{RetryableStatusCodes: []int{500}}}, 500, false, false, true},
+		{"network", errors.New("read tcp 10.0.0.1:443: connection reset by peer"), nil, 0, false, false, true},
+		{"cancelled", context.Canceled, nil, 0, false, false, false},
+		{"not found", errors.New("[ENF] entity not found"), nil, 0, false, false, false},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			err := c.opts.classify(c.err)
+			assert.ErrorIs(t, err, c.err)
+			var statusErr *StatusError
+			if assert.Equal(t, c.status != 0, errors.As(err, &statusErr)) && c.status != 0 {
+				assert.Equal(t, c.status, statusErr.StatusCode)
+			}
+			assert.Equal(t, c.unauthorized, errors.Is(err, ErrUnauthorized))
+			assert.Equal(t, c.forbidden, errors.Is(err, ErrForbidden))
+			assert.Equal(t, c.transient, errors.Is(err, ErrTransient))
+			assert.Equal(t, err, c.opts.classify(err))
+		})
+	}
+	t.Run("call", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}}
+		_, err := call(context.Background(), client, "GetOrgList", func() (int, error) {
+			return 0, errors.New("error retrieving org list: API Error: 403: Forbidden")
+		})
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.NoError(t, client.classify(nil))
+	})
+}
@@ -0,0 +1,117 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// Logger records vCloud requests, retries, re-authentication, aggregation, and errors that are
+// reported as zero usage to logger. If not set, nothing is logged.
+func Logger(logger *slog.Logger) Option {
+	return func(opts *Options) {
+		opts.Logger = logger
+	}
+}
+
+// LogLevel sets the level of the records written to the Logger. If not set, slog.LevelDebug is
+// used, so that nothing is logged unless the logger is enabled for debug records.
+func LogLevel(level slog.Leveler) Option {
+	return func(opts *Options) {
+		opts.LogLevel = level
+	}
+}
+
+// log writes a record to the configured logger, if any.
+func (opts *Options) log(ctx context.Context, msg string, attrs ...slog.Attr) {
+	if opts == nil || opts.Logger == nil {
+		return
+	}
+	level := slog.LevelDebug
+	if opts.LogLevel != nil {
+		level = opts.LogLevel.Level()
+	}
+	opts.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// log writes a record to the client's logger, if any.
+func (client *Client) log(ctx context.Context, msg string, attrs ...slog.Attr) {
+	if client == nil {
+		return
+	}
+	client.opts.log(ctx, msg, attrs...)
+}
+
+// errAttr returns a log attribute holding err, or an empty attribute, which is ignored by slog
+// handlers, if err is nil.
+func errAttr(err error) slog.Attr {
+	if err == nil {
+		return slog.Attr{}
+	}
+	return slog.Any("err", err)
+}
+
+// logAttrs returns the log attributes identifying the VDC.
+func (vdc *VDC) logAttrs() []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("vdc_id", vdc.Obj.Vdc.ID),
+		slog.String("vdc_name", vdc.Obj.Vdc.Name),
+	}
+	if vdc.AdminOrg != nil && vdc.AdminOrg.AdminOrg != nil {
+		attrs = append(attrs, slog.String("org_id", vdc.AdminOrg.AdminOrg.ID))
+	}
+	return attrs
+}
+
+// swallowed logs err, which is not returned by a method that reports zero usage on failure.
+func (vdc *VDC) swallowed(method string, err error) {
+	if err == nil {
+		return
+	}
+	attrs := append(vdc.logAttrs(), slog.String("method", method), errAttr(err))
+	vdc.Client.log(context.Background(), "reporting zero usage after error", attrs...)
+}
+
+// swallowed logs errs, which are not returned by a method that excludes the usage of VDCs that
+// fail from its total.
+func (vdcs VDCs) swallowed(method string, errs []error) {
+	if len(vdcs) == 0 {
+		return
+	}
+	for _, err := range errs {
+		attrs := []slog.Attr{slog.String("method", method)}
+		var vdcErr *VDCError
+		if errors.As(err, &vdcErr) {
+			attrs = append(attrs, slog.String("vdc_id", vdcErr.ID), slog.String("vdc_name", vdcErr.Name))
+			if vdcErr.OrgID != "" {
+				attrs = append(attrs, slog.String("org_id", vdcErr.OrgID))
+			}
+			err = vdcErr.Err
+		}
+		vdcs[0].Client.log(context.Background(), "excluding VDC from total after error", append(attrs, errAttr(err))...)
+	}
+}
+
+// loggedTransport is an HTTP transport that logs each request.
+type loggedTransport struct {
+	opts *Options
+	next http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (lt *loggedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	res, err := lt.next.RoundTrip(req)
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Duration("duration", time.Since(started)),
+	}
+	if res != nil {
+		attrs = append(attrs, slog.Int("status", res.StatusCode))
+	}
+	lt.opts.log(req.Context(), "vCloud request", append(attrs, errAttr(err))...)
+	return res, err
+}
@@ -0,0 +1,82 @@
+package vcdusage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+func Test_log(t *testing.T) {
+	unavailable := errors.New("API Error: 503: Service Unavailable")
+	t.Run("operations and retries", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		policy := DefaultRetryPolicy()
+		policy.InitialBackoff = time.Millisecond
+		client := &Client{opts: &Options{
+			Logger: slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+			Retry:  &policy,
+		}}
+		attempts := 0
+		_, err := call(context.Background(), client, "GetAdminVDCById", func() (int, error) {
+			attempts++
+			if attempts == 1 {
+				return 0, unavailable
+			}
+			return 1, nil
+		}, slog.String("vdc_id", "vdc-1"))
+		require.NoError(t, err)
+		out := buf.String()
+		assert.Contains(t, out, `msg="retrying vCloud operation" operation=GetAdminVDCById vdc_id=vdc-1 attempt=1`)
+		assert.Contains(t, out, `msg="vCloud operation completed" operation=GetAdminVDCById vdc_id=vdc-1`)
+	})
+	t.Run("debug by default", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		client := &Client{opts: &Options{Logger: slog.New(slog.NewTextHandler(&buf, nil))}}
+		_, err := call(context.Background(), client, "GetOrgList", func() (int, error) { return 1, nil })
+		require.NoError(t, err)
+		assert.Empty(t, buf.String())
+	})
+	t.Run("level", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		client := &Client{opts: &Options{Logger: slog.New(slog.NewTextHandler(&buf, nil)), LogLevel: slog.LevelInfo}}
+		_, err := call(context.Background(), client, "GetOrgList", func() (int, error) { return 1, nil })
+		require.NoError(t, err)
+		assert.Contains(t, buf.String(), "level=INFO")
+	})
+	t.Run("silent", func(t *testing.T) {
+		t.Parallel()
+		client := &Client{opts: &Options{}}
+		_, err := call(context.Background(), client, "GetOrgList", func() (int, error) { return 1, nil })
+		require.NoError(t, err)
+	})
+	t.Run("aggregation", func(t *testing.T) {
+		t.Parallel()
+		var buf bytes.Buffer
+		vdcs := testVDCs(3, 2)
+		vdcs[1].AdminOrg = &govcd.AdminOrg{AdminOrg: &types.AdminOrg{ID: "org-1"}}
+		vdcs[0].Client.opts.Logger = slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		get := func(vdc *VDC, _ context.Context) (uint64, error) {
+			if vdc.Obj.Vdc.ID == "vdc-1" {
+				return 0, unavailable
+			}
+			return 1, nil
+		}
+		total, errs := reduce(context.Background(), vdcs, get, sum[uint64])
+		vdcs.swallowed("CoreCount", errs)
+		assert.Equal(t, uint64(2), total)
+		out := buf.String()
+		assert.Contains(t, out, `msg="aggregated VDCs" vdcs=3 failed=1 concurrency=2`)
+		assert.Contains(t, out, `msg="excluding VDC from total after error" method=CoreCount vdc_id=vdc-1 vdc_name="VDC 1" org_id=org-1 err="API Error: 503: Service Unavailable"`)
+	})
+}
@@ -0,0 +1,171 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/destel/rill"
+)
+
+// ErrNoSites indicates a MultiClient was created without any sites.
+var ErrNoSites = errors.New("at least one site required")
+
+// MultiClient queries several vCloud sites concurrently. Each site is a Client identified by a
+// name, and a failure at one site does not affect the results of the others.
+type MultiClient struct {
+	sites map[string]*Client
+	names []string
+}
+
+// SiteError wraps an error encountered while querying a single site.
+type SiteError struct {
+	Site string
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *SiteError) Error() string {
+	return fmt.Sprintf("site '%s': %s", e.Site, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SiteError) Unwrap() error {
+	return e.Err
+}
+
+// SiteResult is the result of a query against a single site. If the query failed, Err is a
+// *SiteError and Value holds the partial result of the site, if any, such as the report of the VDCs
+// that were collected successfully.
+type SiteResult[T any] struct {
+	Site  string
+	Value T
+	Err   error
+}
+
+// MultiReport is the usage of every organization at every site, with the totals of all sites that
+// were collected successfully.
+type MultiReport struct {
+	Sites       []SiteResult[*ProviderReport]
+	Total       UsageTotals
+	StartedAt   time.Time
+	CollectedAt time.Time
+}
+
+// NewMultiClient creates a MultiClient from clients keyed by site name.
+func NewMultiClient(sites map[string]*Client) (*MultiClient, error) {
+	if len(sites) == 0 {
+		return nil, ErrNoSites
+	}
+	names := make([]string, 0, len(sites))
+	for name, client := range sites {
+		if client == nil {
+			return nil, fmt.Errorf("client for site '%s' is nil", name)
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	mc := &MultiClient{
+		sites: sites,
+		names: names,
+	}
+	return mc, nil
+}
+
+// Sites returns the names of all sites, sorted.
+func (mc *MultiClient) Sites() []string {
+	return slices.Clone(mc.names)
+}
+
+// Site returns the client of a site.
+func (mc *MultiClient) Site(name string) (*Client, bool) {
+	client, ok := mc.sites[name]
+	return client, ok
+}
+
+// eachSite runs get against every site concurrently, returning the result of each site in site
+// name order.
+func eachSite[T any](ctx context.Context, mc *MultiClient, get func(*Client, context.Context) (T, error)) []SiteResult[T] {
+	results := rill.OrderedMap(rill.FromSlice(mc.names, nil), len(mc.names), func(name string) (SiteResult[T], error) {
+		value, err := get(mc.sites[name], ctx)
+		if err != nil {
+			return SiteResult[T]{Site: name, Value: value, Err: &SiteError{Site: name, Err: err}}, nil
+		}
+		return SiteResult[T]{Site: name, Value: value}, nil
+	})
+	all := make([]SiteResult[T], 0, len(mc.names))
+	for res := range results {
+		all = append(all, res.Value)
+	}
+	return all
+}
+
+// Orgs retrieves all organizations at every site matching all of the provided queries.
+func (mc *MultiClient) Orgs(queries ...OrgQuerySetter) []SiteResult[[]Organization] {
+	return mc.OrgsContext(context.Background(), queries...)
+}
+
+// OrgsContext is the same as Orgs, but aborts if ctx is cancelled.
+func (mc *MultiClient) OrgsContext(ctx context.Context, queries ...OrgQuerySetter) []SiteResult[[]Organization] {
+	return eachSite(ctx, mc, func(client *Client, ctx context.Context) ([]Organization, error) {
+		return client.OrgsContext(ctx, queries...)
+	})
+}
+
+// VDCs retrieves the VDCs of an organization at every site, where org is a URN, a bare UUID, or a
+// name; see Client.ResolveOrg. Sites at which the organization does not exist are omitted.
+func (mc *MultiClient) VDCs(org string) []SiteResult[VDCs] {
+	return mc.VDCsContext(context.Background(), org)
+}
+
+// VDCsContext is the same as VDCs, but aborts if ctx is cancelled.
+func (mc *MultiClient) VDCsContext(ctx context.Context, org string) []SiteResult[VDCs] {
+	results := eachSite(ctx, mc, func(client *Client, ctx context.Context) (VDCs, error) {
+		obj, err := client.ResolveOrgContext(ctx, org)
+		if err != nil {
+			return nil, err
+		}
+		return client.orgVDCs(ctx, obj)
+	})
+	return slices.DeleteFunc(results, func(res SiteResult[VDCs]) bool {
+		return errors.Is(res.Err, ErrOrgNotFound)
+	})
+}
+
+// ProviderSnapshot retrieves the usage of every VDC in every organization at every site. See
+// Client.ProviderSnapshot.
+func (mc *MultiClient) ProviderSnapshot() *MultiReport {
+	return mc.ProviderSnapshotContext(context.Background())
+}
+
+// ProviderSnapshotContext is the same as ProviderSnapshot, but aborts if ctx is cancelled.
+func (mc *MultiClient) ProviderSnapshotContext(ctx context.Context) *MultiReport {
+	started := time.Now()
+	results := eachSite(ctx, mc, (*Client).ProviderSnapshotContext)
+	report := &MultiReport{
+		Sites:     results,
+		StartedAt: started,
+	}
+	for _, res := range results {
+		if res.Err == nil {
+			report.Total.merge(res.Value.Total)
+		}
+	}
+	report.CollectedAt = time.Now()
+	return report
+}
+
+// Close closes the client of every site. See Client.Close.
+func (mc *MultiClient) Close() error {
+	errs := make([]error, 0)
+	for _, res := range eachSite(context.Background(), mc, func(client *Client, _ context.Context) (struct{}, error) {
+		return struct{}{}, client.Close()
+	}) {
+		if res.Err != nil {
+			errs = append(errs, res.Err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,40 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_eachSite(t *testing.T) {
+	t.Run("isolation", func(t *testing.T) {
+		t.Parallel()
+		failing := &Client{}
+		mc, err := NewMultiClient(map[string]*Client{"c": {}, "a": {}, "b": failing})
+		require.NoError(t, err)
+		errSite := errors.New("site unavailable")
+		results := eachSite(context.Background(), mc, func(client *Client, _ context.Context) (int, error) {
+			if client == failing {
+				return 0, errSite
+			}
+			return 1, nil
+		})
+		require.Len(t, results, 3)
+		assert.Equal(t, []string{"a", "b", "c"}, []string{results[0].Site, results[1].Site, results[2].Site})
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, 1, results[0].Value)
+		assert.ErrorIs(t, results[1].Err, errSite)
+		var siteErr *SiteError
+		require.ErrorAs(t, results[1].Err, &siteErr)
+		assert.Equal(t, "b", siteErr.Site)
+		assert.NoError(t, results[2].Err)
+	})
+	t.Run("no sites", func(t *testing.T) {
+		t.Parallel()
+		_, err := NewMultiClient(nil)
+		assert.ErrorIs(t, err, ErrNoSites)
+	})
+}
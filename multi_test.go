@@ -0,0 +1,45 @@
+package vcdusage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.stellar.af/go-vcdusage"
+)
+
+func Test_MultiClient(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	mc, err := vcdusage.NewMultiClient(map[string]*vcdusage.Client{"primary": client})
+	require.NoError(t, err)
+	t.Run("orgs", func(t *testing.T) {
+		t.Parallel()
+		results := mc.Orgs()
+		require.Len(t, results, 1)
+		assert.Equal(t, "primary", results[0].Site)
+		require.NoError(t, results[0].Err)
+		assert.NotEmpty(t, results[0].Value)
+	})
+	t.Run("vdcs", func(t *testing.T) {
+		t.Parallel()
+		results := mc.VDCs(Env.OrgID)
+		require.Len(t, results, 1)
+		require.NoError(t, results[0].Err)
+		assert.Equal(t, Env.Cores, results[0].Value.CoreCount())
+	})
+	t.Run("provider", func(t *testing.T) {
+		t.Parallel()
+		report := mc.ProviderSnapshot()
+		require.Len(t, report.Sites, 1)
+		require.NoError(t, report.Sites[0].Err)
+		assert.Equal(t, report.Sites[0].Value.Total, report.Total)
+	})
+}
@@ -0,0 +1,119 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github.com/destel/rill"
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+// Organization is a summary of a vCloud organization.
+type Organization struct {
+	ID       string
+	Name     string
+	FullName string
+	Enabled  bool
+	Metadata map[string]string
+}
+
+// orgRef identifies an organization in the org list.
+type orgRef struct {
+	ID   string
+	Name string
+}
+
+// Orgs retrieves all organizations visible to the client matching all of the provided queries.
+func (client *Client) Orgs(queries ...OrgQuerySetter) ([]Organization, error) {
+	return client.OrgsContext(context.Background(), queries...)
+}
+
+// OrgsContext is the same as Orgs, but aborts if ctx is cancelled.
+func (client *Client) OrgsContext(ctx context.Context, queries ...OrgQuerySetter) ([]Organization, error) {
+	query := &OrgQuery{
+		Name:    nil,
+		Enabled: nil,
+	}
+	for _, set := range queries {
+		set(query)
+	}
+	refs, err := client.orgRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if query.Name != nil {
+		_refs := make([]orgRef, 0, len(refs))
+		for _, ref := range refs {
+			if query.Name.MatchString(ref.Name) {
+				_refs = append(_refs, ref)
+			}
+		}
+		refs = _refs
+	}
+	results := rill.OrderedMap(rill.FromSlice(refs, nil), client.concurrency(), func(ref orgRef) (Organization, error) {
+		return client.organization(ctx, ref.ID)
+	})
+	orgs := make([]Organization, 0, len(refs))
+	errs := make([]error, 0)
+	for res := range results {
+		if res.Error != nil {
+			errs = append(errs, res.Error)
+			continue
+		}
+		if query.Enabled != nil && res.Value.Enabled != *query.Enabled {
+			continue
+		}
+		orgs = append(orgs, res.Value)
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return orgs, nil
+}
+
+// organization retrieves the summary of a single organization, including its metadata.
+func (client *Client) organization(ctx context.Context, orgID string) (Organization, error) {
+	org, err := client.OrgContext(ctx, orgID)
+	if err != nil {
+		return Organization{}, err
+	}
+	md, err := call(ctx, client, "GetMetadata", org.GetMetadata, slog.String("org_id", org.AdminOrg.ID))
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve metadata for org '%s'", org.AdminOrg.Name)
+		return Organization{}, err
+	}
+	metadata := make(map[string]string, len(md.MetadataEntry))
+	for _, entry := range md.MetadataEntry {
+		if entry.TypedValue != nil {
+			metadata[entry.Key] = entry.TypedValue.Value
+		}
+	}
+	return Organization{
+		ID:       org.AdminOrg.ID,
+		Name:     org.AdminOrg.Name,
+		FullName: org.AdminOrg.FullName,
+		Enabled:  org.AdminOrg.IsEnabled,
+		Metadata: metadata,
+	}, nil
+}
+
+// orgRefs retrieves references to all organizations visible to the client.
+func (client *Client) orgRefs(ctx context.Context) ([]orgRef, error) {
+	list, err := call(ctx, client, "GetOrgList", client.VCD.GetOrgList)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve org list")
+		return nil, err
+	}
+	refs := make([]orgRef, 0, len(list.Org))
+	for _, org := range list.Org {
+		id, err := govcd.GetUuidFromHref(org.HREF, true)
+		if err != nil {
+			err = errorx.Decorate(err, "failed to parse ID of org '%s'", org.Name)
+			return nil, err
+		}
+		refs = append(refs, orgRef{ID: id, Name: org.Name})
+	}
+	return refs, nil
+}
@@ -0,0 +1,39 @@
+package vcdusage
+
+import (
+	"fmt"
+	"regexp"
+)
+
+type OrgQuery struct {
+	Name    *regexp.Regexp
+	Enabled *bool
+}
+
+type OrgQuerySetter func(*OrgQuery)
+
+func OrgWithNameContaining(contains string) OrgQuerySetter {
+	return func(q *OrgQuery) {
+		q.Name = regexp.MustCompile(fmt.Sprintf("(?i).*%s.*", regexp.QuoteMeta(contains)))
+	}
+}
+
+func OrgWithNameMatching(pattern *regexp.Regexp) OrgQuerySetter {
+	return func(q *OrgQuery) {
+		q.Name = pattern
+	}
+}
+
+func OrgEnabled() OrgQuerySetter {
+	return func(q *OrgQuery) {
+		enabled := true
+		q.Enabled = &enabled
+	}
+}
+
+func OrgDisabled() OrgQuerySetter {
+	return func(q *OrgQuery) {
+		enabled := false
+		q.Enabled = &enabled
+	}
+}
@@ -0,0 +1,51 @@
+package vcdusage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.stellar.af/go-vcdusage"
+)
+
+func Test_Orgs(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	orgs, err := client.Orgs()
+	require.NoError(t, err)
+	var org *vcdusage.Organization
+	for i := range orgs {
+		if strings.HasSuffix(orgs[i].ID, Env.OrgID) {
+			org = &orgs[i]
+		}
+	}
+	require.NotNil(t, org, "org '%s' not found", Env.OrgID)
+	t.Run("name", func(t *testing.T) {
+		t.Parallel()
+		matching, err := client.Orgs(vcdusage.OrgWithNameContaining(strings.ToUpper(org.Name)))
+		require.NoError(t, err)
+		require.NotEmpty(t, matching)
+		for _, o := range matching {
+			assert.Contains(t, strings.ToLower(o.Name), strings.ToLower(org.Name))
+		}
+	})
+	t.Run("enabled", func(t *testing.T) {
+		t.Parallel()
+		matching, err := client.Orgs(vcdusage.OrgWithNameContaining(org.Name), vcdusage.OrgEnabled())
+		require.NoError(t, err)
+		assert.NotEmpty(t, matching)
+		matching, err = client.Orgs(vcdusage.OrgWithNameContaining(org.Name), vcdusage.OrgDisabled())
+		require.NoError(t, err)
+		for _, o := range matching {
+			assert.False(t, o.Enabled)
+		}
+	})
+}
@@ -0,0 +1,73 @@
+package vcdusage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// queryPageSize is the number of records requested per page of a typed query, which is the most
+// vCloud returns per page.
+const queryPageSize = 128
+
+// queryPages runs a typed query for the records of queryType matching filter, if set, and calls fn
+// with the results of each page as it is retrieved. filter must already be URL-encoded. Paging
+// stops when all records have been retrieved, or if fn returns an error.
+func (client *Client) queryPages(ctx context.Context, queryType, filter string, fn func(*types.QueryResultRecordsType) error) error {
+	for page := 1; ; page++ {
+		params := map[string]string{
+			"type":     queryType,
+			"page":     strconv.Itoa(page),
+			"pageSize": strconv.Itoa(queryPageSize),
+			"sortAsc":  "name",
+		}
+		if filter != "" {
+			params["filter"] = filter
+			params["filterEncoded"] = "true"
+		}
+		res, err := call(ctx, client, "Query", func() (govcd.Results, error) {
+			return client.VCD.Client.QueryWithNotEncodedParams(nil, params)
+		}, slog.String("query_type", queryType), slog.Int("page", page))
+		if err != nil {
+			err = errorx.Decorate(err, "failed to query page %d of '%s' records", page, queryType)
+			return err
+		}
+		if err := fn(res.Results); err != nil {
+			return err
+		}
+		if res.Results.PageSize == 0 || page*res.Results.PageSize >= int(res.Results.Total) {
+			return nil
+		}
+	}
+}
+
+// queryAll runs a typed query for every record of queryType matching filter, if set, and returns
+// the records selected from each page by records.
+func queryAll[T any](ctx context.Context, client *Client, queryType, filter string, records func(*types.QueryResultRecordsType) []T) ([]T, error) {
+	all := make([]T, 0)
+	err := client.queryPages(ctx, queryType, filter, func(res *types.QueryResultRecordsType) error {
+		all = append(all, records(res)...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return all, nil
+}
+
+// uuidOf returns the UUID at the end of an HREF or URN referencing a vCloud object.
+func uuidOf(ref string) string {
+	return ref[strings.LastIndexAny(ref, "/:")+1:]
+}
+
+// urnOf returns the URN of the vCloud object of kind, e.g. 'vdc', referenced by an HREF or URN.
+// HREFs of some objects, such as VMs, prefix the UUID with the kind, e.g. 'vm-'.
+func urnOf(kind, ref string) string {
+	return fmt.Sprintf("urn:vcloud:%s:%s", kind, strings.TrimPrefix(uuidOf(ref), kind+"-"))
+}
@@ -0,0 +1,44 @@
+package vcdusage
+
+import (
+	"errors"
+	"net/http"
+
+	"golang.org/x/time/rate"
+)
+
+// ErrCfgRateLimit indicates a rate limiter would stop allowing requests, because its limit is not
+// positive or its burst is less than 1.
+var ErrCfgRateLimit = errors.New("rate limit must be positive with a burst of at least 1")
+
+// RateLimit limits the rate of HTTP requests to vCloud to rps requests per second on average, with
+// bursts of up to burst requests. rps must be positive, or rate.Inf to not limit requests. The
+// limit is shared by all requests made by the client, including those made concurrently by VDCs
+// methods.
+func RateLimit(rps float64, burst int) Option {
+	return func(opts *Options) {
+		opts.RateLimiter = rate.NewLimiter(rate.Limit(rps), burst)
+	}
+}
+
+// RateLimiter is the same as RateLimit, but uses an existing limiter, which may be shared by
+// several clients that communicate with the same vCloud host.
+func RateLimiter(limiter *rate.Limiter) Option {
+	return func(opts *Options) {
+		opts.RateLimiter = limiter
+	}
+}
+
+// rateLimited is an HTTP transport that waits for a limiter to allow each request.
+type rateLimited struct {
+	limiter *rate.Limiter
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rl *rateLimited) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := rl.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return rl.next.RoundTrip(req)
+}
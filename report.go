@@ -0,0 +1,172 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/destel/rill"
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+// UsageTotals is the combined usage of one or more VDCs. VDCs whose cores or storage are
+// unavailable contribute nothing to them.
+type UsageTotals struct {
+	Cores            uint64
+	Memory           DataStorage
+	Storage          DataStorage
+	StorageAll       DataStorage
+	VMCount          uint64
+	PoweredOnVMCount uint64
+}
+
+// add adds the usage of a VDC snapshot to the totals.
+func (t *UsageTotals) add(snap *UsageSnapshot) {
+	t.Cores += snap.Cores
+	t.Memory += snap.Memory
+	t.Storage += snap.Storage
+	t.StorageAll += snap.StorageAll
+	t.VMCount += snap.VMCount
+	t.PoweredOnVMCount += snap.PoweredOnVMCount
+}
+
+// merge adds other totals to the totals.
+func (t *UsageTotals) merge(other UsageTotals) {
+	t.Cores += other.Cores
+	t.Memory += other.Memory
+	t.Storage += other.Storage
+	t.StorageAll += other.StorageAll
+	t.VMCount += other.VMCount
+	t.PoweredOnVMCount += other.PoweredOnVMCount
+}
+
+// OrgReport is the usage of every VDC in an organization, with the organization's totals.
+type OrgReport struct {
+	ID          string
+	Name        string
+	VDCs        []*UsageSnapshot
+	Total       UsageTotals
+	StartedAt   time.Time
+	CollectedAt time.Time
+}
+
+// ProviderReport is the usage of every organization in vCloud, with the provider-wide totals.
+type ProviderReport struct {
+	Orgs        []*OrgReport
+	Total       UsageTotals
+	StartedAt   time.Time
+	CollectedAt time.Time
+}
+
+// newOrgReport creates a report for the organization with id and name from the snapshots of its
+// VDCs.
+func newOrgReport(id, name string, snaps []*UsageSnapshot, started time.Time) *OrgReport {
+	report := &OrgReport{
+		ID:        id,
+		Name:      name,
+		VDCs:      snaps,
+		StartedAt: started,
+	}
+	for _, snap := range snaps {
+		report.Total.add(snap)
+	}
+	report.CollectedAt = time.Now()
+	return report
+}
+
+// OrgSnapshot retrieves the usage of every VDC in an organization.
+func (client *Client) OrgSnapshot(orgID string) (*OrgReport, error) {
+	return client.OrgSnapshotContext(context.Background(), orgID)
+}
+
+// OrgSnapshotContext is the same as OrgSnapshot, but aborts if ctx is cancelled. If the usage of
+// any VDC cannot be retrieved, the report of the remaining VDCs is returned with an error
+// identifying each failed VDC.
+func (client *Client) OrgSnapshotContext(ctx context.Context, orgID string) (*OrgReport, error) {
+	started := time.Now()
+	org, err := client.OrgContext(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	vdcs, err := client.orgVDCs(ctx, org)
+	if err != nil {
+		return nil, err
+	}
+	snaps, errs := each(ctx, vdcs, (*VDC).SnapshotContext)
+	report := newOrgReport(org.AdminOrg.ID, org.AdminOrg.Name, snaps, started)
+	if len(errs) != 0 {
+		err = errorx.Decorate(errors.Join(errs...), "failed to collect usage for org '%s'", orgID)
+		return report, err
+	}
+	return report, nil
+}
+
+// ProviderSnapshot retrieves the usage of every VDC in every organization visible to the client.
+func (client *Client) ProviderSnapshot() (*ProviderReport, error) {
+	return client.ProviderSnapshotContext(context.Background())
+}
+
+// ProviderSnapshotContext is the same as ProviderSnapshot, but aborts if ctx is cancelled. The VDCs
+// of all organizations share a single pool limited by the Concurrency option. If any organization
+// or VDC cannot be retrieved, the report of the remaining organizations and VDCs is returned with
+// an error identifying each failed organization and VDC.
+func (client *Client) ProviderSnapshotContext(ctx context.Context) (*ProviderReport, error) {
+	started := time.Now()
+	refs, err := client.orgRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type orgVDCs struct {
+		org  *govcd.AdminOrg
+		vdcs VDCs
+	}
+	results := rill.OrderedMap(rill.FromSlice(refs, nil), client.concurrency(), func(ref orgRef) (orgVDCs, error) {
+		org, err := client.OrgContext(ctx, ref.ID)
+		if err != nil {
+			err = errorx.Decorate(err, "failed to collect usage for org '%s'", ref.Name)
+			return orgVDCs{}, err
+		}
+		vdcs, err := client.orgVDCs(ctx, org)
+		if err != nil {
+			err = errorx.Decorate(err, "failed to collect usage for org '%s'", ref.Name)
+			return orgVDCs{}, err
+		}
+		return orgVDCs{org: org, vdcs: vdcs}, nil
+	})
+	orgs := make([]orgVDCs, 0, len(refs))
+	all := make(VDCs, 0)
+	errs := make([]error, 0)
+	for res := range results {
+		if res.Error != nil {
+			errs = append(errs, res.Error)
+			continue
+		}
+		orgs = append(orgs, res.Value)
+		all = append(all, res.Value.vdcs...)
+	}
+	snaps, vdcErrs := each(ctx, all, (*VDC).SnapshotContext)
+	errs = append(errs, vdcErrs...)
+	byOrg := make(map[string][]*UsageSnapshot)
+	for _, snap := range snaps {
+		byOrg[snap.OrgID] = append(byOrg[snap.OrgID], snap)
+	}
+	report := &ProviderReport{
+		Orgs:      make([]*OrgReport, 0, len(orgs)),
+		StartedAt: started,
+	}
+	for _, o := range orgs {
+		snaps := byOrg[o.org.AdminOrg.ID]
+		if snaps == nil {
+			snaps = make([]*UsageSnapshot, 0)
+		}
+		orgReport := newOrgReport(o.org.AdminOrg.ID, o.org.AdminOrg.Name, snaps, started)
+		report.Orgs = append(report.Orgs, orgReport)
+		report.Total.merge(orgReport.Total)
+	}
+	report.CollectedAt = time.Now()
+	if len(errs) != 0 {
+		return report, errors.Join(errs...)
+	}
+	return report, nil
+}
@@ -0,0 +1,54 @@
+package vcdusage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.stellar.af/go-vcdusage"
+)
+
+func Test_Report(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	t.Run("org", func(t *testing.T) {
+		t.Parallel()
+		report, err := client.OrgSnapshot(Env.OrgID)
+		require.NoError(t, err)
+		assert.NotEmpty(t, report.VDCs, "no VDCs")
+		assert.Equal(t, Env.Cores, report.Total.Cores, "mismatching core count: %v != %v", Env.Cores, report.Total.Cores)
+		assert.Equal(t, Env.Memory, report.Total.Memory.GB(), "mismatching memory: %v != %v", Env.Memory, report.Total.Memory.GB())
+		assert.Equal(t, Env.Storage, report.Total.Storage.GB(), "mismatching storage: %v != %v", Env.Storage, report.Total.Storage.GB())
+	})
+	t.Run("provider", func(t *testing.T) {
+		t.Parallel()
+		report, err := client.ProviderSnapshot()
+		require.NoError(t, err)
+		var org *vcdusage.OrgReport
+		for _, o := range report.Orgs {
+			if strings.HasSuffix(o.ID, Env.OrgID) {
+				org = o
+			}
+		}
+		require.NotNil(t, org, "org '%s' not in provider report", Env.OrgID)
+		assert.Equal(t, Env.Cores, org.Total.Cores, "mismatching core count: %v != %v", Env.Cores, org.Total.Cores)
+		assert.GreaterOrEqual(t, report.Total.Cores, org.Total.Cores, "provider total less than org total")
+	})
+	t.Run("bulk", func(t *testing.T) {
+		t.Parallel()
+		bulk, err := client.BulkSnapshot()
+		require.NoError(t, err)
+		report, err := client.ProviderSnapshot()
+		require.NoError(t, err)
+		assert.Equal(t, report.Total, bulk.Total, "bulk totals differ from per-VDC totals")
+		assert.Len(t, bulk.Orgs, len(report.Orgs))
+	})
+}
@@ -0,0 +1,152 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+// uuidPattern matches a bare UUID.
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// isID determines if ref is a vCloud URN or a bare UUID rather than a name.
+func isID(ref string) bool {
+	return strings.HasPrefix(ref, "urn:vcloud:") || uuidPattern.MatchString(ref)
+}
+
+// matchName returns the index of the name in names equal to name or, if none is, the index of the
+// single name equal to name when compared case-insensitively. If no name matches, -1 is returned.
+func matchName(names []string, name string) (int, error) {
+	for i, n := range names {
+		if n == name {
+			return i, nil
+		}
+	}
+	match := -1
+	for i, n := range names {
+		if strings.EqualFold(n, name) {
+			if match != -1 {
+				return -1, ErrAmbiguousName
+			}
+			match = i
+		}
+	}
+	return match, nil
+}
+
+// OrgByName retrieves a vCloud Organization object by its name.
+func (client *Client) OrgByName(name string) (*govcd.AdminOrg, error) {
+	return client.OrgByNameContext(context.Background(), name)
+}
+
+// OrgByNameContext is the same as OrgByName, but aborts if ctx is cancelled.
+func (client *Client) OrgByNameContext(ctx context.Context, name string) (*govcd.AdminOrg, error) {
+	refs, err := client.orgRefs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		names = append(names, ref.Name)
+	}
+	i, err := matchName(names, name)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to resolve org '%s'", name)
+		return nil, err
+	}
+	if i == -1 {
+		err = errorx.Decorate(ErrOrgNotFound, "failed to resolve org '%s'", name)
+		return nil, err
+	}
+	return client.OrgContext(ctx, refs[i].ID)
+}
+
+// ResolveOrg retrieves a vCloud Organization object by its URN, bare UUID, or name.
+func (client *Client) ResolveOrg(ref string) (*govcd.AdminOrg, error) {
+	return client.ResolveOrgContext(context.Background(), ref)
+}
+
+// ResolveOrgContext is the same as ResolveOrg, but aborts if ctx is cancelled.
+func (client *Client) ResolveOrgContext(ctx context.Context, ref string) (*govcd.AdminOrg, error) {
+	if !isID(ref) {
+		return client.OrgByNameContext(ctx, ref)
+	}
+	org, err := client.OrgContext(ctx, ref)
+	if errors.Is(err, ErrOrgNotFound) && uuidPattern.MatchString(ref) {
+		// An org may be named like a UUID.
+		return client.OrgByNameContext(ctx, ref)
+	}
+	return org, err
+}
+
+// VDCByName retrieves a single VDC by its name and the name of its organization, and provides a
+// wrapper for utilization functions for the VDC.
+func (client *Client) VDCByName(orgName, vdcName string) (*VDC, error) {
+	return client.VDCByNameContext(context.Background(), orgName, vdcName)
+}
+
+// VDCByNameContext is the same as VDCByName, but aborts if ctx is cancelled.
+func (client *Client) VDCByNameContext(ctx context.Context, orgName, vdcName string) (*VDC, error) {
+	org, err := client.OrgByNameContext(ctx, orgName)
+	if err != nil {
+		return nil, err
+	}
+	return client.vdcByName(ctx, org, vdcName)
+}
+
+// ResolveVDC retrieves a single VDC by the URN, bare UUID, or name of both the VDC and its
+// organization, and provides a wrapper for utilization functions for the VDC.
+func (client *Client) ResolveVDC(orgRef, vdcRef string) (*VDC, error) {
+	return client.ResolveVDCContext(context.Background(), orgRef, vdcRef)
+}
+
+// ResolveVDCContext is the same as ResolveVDC, but aborts if ctx is cancelled.
+func (client *Client) ResolveVDCContext(ctx context.Context, orgRef, vdcRef string) (*VDC, error) {
+	org, err := client.ResolveOrgContext(ctx, orgRef)
+	if err != nil {
+		return nil, err
+	}
+	if !isID(vdcRef) {
+		return client.vdcByName(ctx, org, vdcRef)
+	}
+	vdc, err := client.orgVDC(ctx, org, vdcRef)
+	if errors.Is(err, ErrVDCNotFound) && uuidPattern.MatchString(vdcRef) {
+		// A VDC may be named like a UUID.
+		return client.vdcByName(ctx, org, vdcRef)
+	}
+	return vdc, err
+}
+
+// vdcByName retrieves a single VDC in an organization by its name.
+func (client *Client) vdcByName(ctx context.Context, org *govcd.AdminOrg, name string) (*VDC, error) {
+	names := make([]string, 0)
+	hrefs := make([]string, 0)
+	if org.AdminOrg.Vdcs != nil {
+		for _, ref := range org.AdminOrg.Vdcs.Vdcs {
+			names = append(names, ref.Name)
+			hrefs = append(hrefs, ref.HREF)
+		}
+	}
+	i, err := matchName(names, name)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to resolve VDC '%s' in org '%s'", name, org.AdminOrg.Name)
+		return nil, err
+	}
+	if i == -1 {
+		err = errorx.Decorate(ErrVDCNotFound, "failed to resolve VDC '%s' in org '%s'", name, org.AdminOrg.Name)
+		return nil, err
+	}
+	obj, err := call(ctx, client, "GetVDCByHref", func() (*govcd.Vdc, error) {
+		return org.GetVDCByHref(hrefs[i])
+	}, slog.String("org_id", org.AdminOrg.ID), slog.String("vdc_name", name))
+	if err != nil {
+		err = errorx.Decorate(notFound(err, ErrVDCNotFound), "failed to retrieve VDC '%s' for org '%s'", name, org.AdminOrg.Name)
+		return nil, err
+	}
+	return &VDC{Obj: obj, AdminOrg: org, Client: client}, nil
+}
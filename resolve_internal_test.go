@@ -0,0 +1,37 @@
+package vcdusage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_matchName(t *testing.T) {
+	type caseT struct {
+		name   string
+		names  []string
+		lookup string
+		want   int
+		err    error
+	}
+	cases := []caseT{
+		{"exact", []string{"other", "acme"}, "acme", 1, nil},
+		{"case-insensitive", []string{"other", "ACME"}, "acme", 1, nil},
+		{"exact after case-insensitive", []string{"acme", "ACME", "Acme"}, "Acme", 2, nil},
+		{"ambiguous", []string{"acme", "ACME"}, "Acme", -1, ErrAmbiguousName},
+		{"missing", []string{"other"}, "acme", -1, nil},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			i, err := matchName(c.names, c.lookup)
+			if c.err != nil {
+				assert.ErrorIs(t, err, c.err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, c.want, i)
+		})
+	}
+}
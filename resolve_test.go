@@ -0,0 +1,58 @@
+package vcdusage_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.stellar.af/go-vcdusage"
+)
+
+func Test_Resolve(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	org, err := client.Org(Env.OrgID)
+	require.NoError(t, err)
+	vdc, err := client.VDC(Env.OrgID, Env.VdcID)
+	require.NoError(t, err)
+	t.Run("org by name", func(t *testing.T) {
+		t.Parallel()
+		byName, err := client.OrgByName(strings.ToUpper(org.AdminOrg.Name))
+		require.NoError(t, err)
+		assert.Equal(t, org.AdminOrg.ID, byName.AdminOrg.ID)
+	})
+	t.Run("resolve org", func(t *testing.T) {
+		t.Parallel()
+		for _, ref := range []string{org.AdminOrg.ID, Env.OrgID, org.AdminOrg.Name} {
+			resolved, err := client.ResolveOrg(ref)
+			require.NoError(t, err, ref)
+			assert.Equal(t, org.AdminOrg.ID, resolved.AdminOrg.ID, ref)
+		}
+	})
+	t.Run("VDC by name", func(t *testing.T) {
+		t.Parallel()
+		byName, err := client.VDCByName(org.AdminOrg.Name, vdc.Obj.Vdc.Name)
+		require.NoError(t, err)
+		assert.Equal(t, vdc.Obj.Vdc.ID, byName.Obj.Vdc.ID)
+		resolved, err := client.ResolveVDC(Env.OrgID, vdc.Obj.Vdc.Name)
+		require.NoError(t, err)
+		assert.Equal(t, vdc.Obj.Vdc.ID, resolved.Obj.Vdc.ID)
+	})
+	t.Run("not found", func(t *testing.T) {
+		t.Parallel()
+		_, err := client.ResolveOrg("this org does not exist")
+		assert.ErrorIs(t, err, vcdusage.ErrOrgNotFound)
+		_, err = client.ResolveOrg("00000000-0000-0000-0000-000000000000")
+		assert.ErrorIs(t, err, vcdusage.ErrOrgNotFound)
+		_, err = client.VDCByName(org.AdminOrg.Name, "this VDC does not exist")
+		assert.ErrorIs(t, err, vcdusage.ErrVDCNotFound)
+	})
+}
@@ -0,0 +1,146 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/joomcode/errorx"
+)
+
+// ErrCfgRetry indicates a retry policy is invalid.
+var ErrCfgRetry = errors.New("invalid retry policy")
+
+// RetryPolicy controls how govcd operations that fail with a transient error are retried.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times an operation is attempted, including the first
+	// attempt.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry.
+	InitialBackoff time.Duration
+	// MaxBackoff is the maximum delay between two attempts.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the delay is multiplied by after each retry.
+	Multiplier float64
+	// Jitter is the fraction of each delay, between 0 and 1, that is randomized so that concurrent
+	// operations do not retry in lockstep.
+	Jitter float64
+	// RetryableStatusCodes are the HTTP status codes of vCloud responses that are retried. Network
+	// errors such as reset connections and timeouts are always retried.
+	RetryableStatusCodes []int
+}
+
+// DefaultRetryPolicy returns a retry policy that attempts each operation up to 4 times, waiting
+// between 500 milliseconds and 10 seconds between attempts, and retries 429, 502, 503, and 504
+// responses.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    4,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     10 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		RetryableStatusCodes: []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		},
+	}
+}
+
+// Retry retries govcd operations that fail with a transient error according to policy. If not set,
+// operations are not retried. See DefaultRetryPolicy.
+func Retry(policy RetryPolicy) Option {
+	return func(opts *Options) {
+		opts.Retry = &policy
+	}
+}
+
+// Validate ensures the policy's parameters are in range.
+func (policy *RetryPolicy) Validate() error {
+	switch {
+	case policy.MaxAttempts < 1:
+		return errorx.Decorate(ErrCfgRetry, "max attempts must be at least 1")
+	case policy.InitialBackoff < 0 || policy.MaxBackoff < 0:
+		return errorx.Decorate(ErrCfgRetry, "backoff must not be negative")
+	case policy.Multiplier < 1:
+		return errorx.Decorate(ErrCfgRetry, "multiplier must be at least 1")
+	case policy.Jitter < 0 || policy.Jitter > 1:
+		return errorx.Decorate(ErrCfgRetry, "jitter must be between 0 and 1")
+	}
+	return nil
+}
+
+// networkErrors are fragments of the text of network errors that are retried. govcd flattens most
+// errors to strings, so they cannot be identified by type.
+var networkErrors = []string{
+	"connection reset by peer",
+	"connection refused",
+	"broken pipe",
+	"unexpected EOF",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"server closed idle connection",
+}
+
+// retryable determines if err is a transient error that is retried by the policy.
+func (policy *RetryPolicy) retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	if code := statusCode(err); code != 0 {
+		return slices.Contains(policy.RetryableStatusCodes, code)
+	}
+	msg := err.Error()
+	for _, fragment := range networkErrors {
+		if strings.Contains(msg, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff returns the delay before the attempt following attempt.
+func (policy *RetryPolicy) backoff(attempt int) time.Duration {
+	d := float64(policy.InitialBackoff) * math.Pow(policy.Multiplier, float64(attempt-1))
+	if limit := float64(policy.MaxBackoff); policy.MaxBackoff > 0 && d > limit {
+		d = limit
+	}
+	d -= d * policy.Jitter * rand.Float64()
+	return time.Duration(d)
+}
+
+// retry executes fn until it succeeds, fails with an error that is not transient, or the client's
+// retry policy is exhausted. Waiting between attempts is aborted if ctx is cancelled, in which case
+// the error of the last attempt is returned. Retries are logged with attrs.
+func retry[T any](ctx context.Context, client *Client, attrs []slog.Attr, fn func() (T, error)) (T, error) {
+	if client == nil || client.opts == nil || client.opts.Retry == nil {
+		return fn()
+	}
+	policy := client.opts.Retry
+	for attempt := 1; ; attempt++ {
+		value, err := fn()
+		if err == nil || attempt >= policy.MaxAttempts || !policy.retryable(err) {
+			if err != nil && attempt > 1 {
+				err = errorx.Decorate(err, "failed after %d attempts", attempt)
+			}
+			return value, err
+		}
+		backoff := policy.backoff(attempt)
+		client.log(ctx, "retrying vCloud operation", append(attrs, slog.Int("attempt", attempt), slog.Duration("backoff", backoff), errAttr(err))...)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return value, err
+		case <-timer.C:
+		}
+	}
+}
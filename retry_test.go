@@ -0,0 +1,116 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRetryPolicy() RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.InitialBackoff = time.Millisecond
+	policy.MaxBackoff = 5 * time.Millisecond
+	return policy
+}
+
+func Test_retry(t *testing.T) {
+	unavailable := errors.New("error retrieving VDC: API Error: 503: Service Unavailable")
+	t.Run("retryable", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultRetryPolicy()
+		assert.True(t, policy.retryable(unavailable))
+		assert.True(t, policy.retryable(errors.New("Get \"https://vcd.example.com/api\": read tcp: connection reset by peer")))
+		assert.False(t, policy.retryable(errors.New("API Error: 403: Forbidden")))
+		assert.False(t, policy.retryable(errors.New("[ENF] entity not found")))
+		assert.False(t, policy.retryable(context.Canceled))
+	})
+	t.Run("backoff", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultRetryPolicy()
+		for attempt := 1; attempt < 10; attempt++ {
+			d := policy.backoff(attempt)
+			assert.LessOrEqual(t, d, policy.MaxBackoff)
+			assert.GreaterOrEqual(t, d, time.Duration(float64(min(policy.InitialBackoff<<(attempt-1), policy.MaxBackoff))*(1-policy.Jitter)))
+		}
+	})
+	t.Run("succeeds", func(t *testing.T) {
+		t.Parallel()
+		policy := testRetryPolicy()
+		client := &Client{opts: &Options{Retry: &policy}}
+		attempts := 0
+		value, err := retry(context.Background(), client, nil, func() (int, error) {
+			attempts++
+			if attempts < 3 {
+				return 0, unavailable
+			}
+			return 1, nil
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 1, value)
+		assert.Equal(t, 3, attempts)
+	})
+	t.Run("exhausted", func(t *testing.T) {
+		t.Parallel()
+		policy := testRetryPolicy()
+		client := &Client{opts: &Options{Retry: &policy}}
+		attempts := 0
+		_, err := retry(context.Background(), client, nil, func() (int, error) {
+			attempts++
+			return 0, unavailable
+		})
+		assert.ErrorIs(t, err, unavailable)
+		assert.Equal(t, policy.MaxAttempts, attempts)
+	})
+	t.Run("not retryable", func(t *testing.T) {
+		t.Parallel()
+		policy := testRetryPolicy()
+		client := &Client{opts: &Options{Retry: &policy}}
+		attempts := 0
+		forbidden := errors.New("API Error: 403: Forbidden")
+		_, err := retry(context.Background(), client, nil, func() (int, error) {
+			attempts++
+			return 0, forbidden
+		})
+		assert.ErrorIs(t, err, forbidden)
+		assert.Equal(t, 1, attempts)
+	})
+	t.Run("no policy", func(t *testing.T) {
+		t.Parallel()
+		attempts := 0
+		_, err := retry(context.Background(), &Client{opts: &Options{}}, nil, func() (int, error) {
+			attempts++
+			return 0, unavailable
+		})
+		assert.ErrorIs(t, err, unavailable)
+		assert.Equal(t, 1, attempts)
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultRetryPolicy()
+		policy.InitialBackoff = time.Hour
+		client := &Client{opts: &Options{Retry: &policy}}
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		attempts := 0
+		_, err := retry(ctx, client, nil, func() (int, error) {
+			attempts++
+			return 0, unavailable
+		})
+		assert.ErrorIs(t, err, unavailable)
+		assert.Equal(t, 1, attempts)
+	})
+	t.Run("validate", func(t *testing.T) {
+		t.Parallel()
+		policy := DefaultRetryPolicy()
+		assert.NoError(t, policy.Validate())
+		policy.Jitter = 2
+		assert.ErrorIs(t, policy.Validate(), ErrCfgRetry)
+		policy = DefaultRetryPolicy()
+		policy.MaxAttempts = 0
+		assert.ErrorIs(t, policy.Validate(), ErrCfgRetry)
+	})
+}
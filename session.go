@@ -0,0 +1,74 @@
+package vcdusage
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// session guards the authentication state of a client. Requests hold the session while they
+// execute, so the client is never re-authenticated while a request is in flight.
+type session struct {
+	mu sync.RWMutex
+	// generation is incremented each time the client is re-authenticated.
+	generation uint64
+	// closed is set when the client starts closing, before it waits for requests in flight, so
+	// that no further requests are started.
+	closed atomic.Bool
+}
+
+// acquire holds the session for a request and returns the current generation. If the client has
+// been closed, the session is not held and ErrClientClosed is returned.
+func (s *session) acquire() (uint64, error) {
+	if s.closed.Load() {
+		return 0, ErrClientClosed
+	}
+	s.mu.RLock()
+	if s.closed.Load() {
+		s.mu.RUnlock()
+		return 0, ErrClientClosed
+	}
+	return s.generation, nil
+}
+
+// release releases a session held by acquire.
+func (s *session) release() {
+	s.mu.RUnlock()
+}
+
+// canReauthenticate determines if the client holds the credentials required to re-authenticate.
+// A bearer token cannot be renewed once it has expired.
+func (client *Client) canReauthenticate() bool {
+	return client.opts != nil && client.VCD != nil && client.opts.BearerToken == ""
+}
+
+// reauthenticate re-authenticates the client with its stored credentials, unless it has already
+// been re-authenticated since gen was acquired, e.g. by a concurrent request that also found the
+// session expired. OnReauthenticate is called once the session is released, so that it may use
+// the client.
+func (client *Client) reauthenticate(gen uint64) error {
+	renewed, err := client.renewSession(gen)
+	if renewed && client.opts.OnReauthenticate != nil {
+		client.opts.OnReauthenticate(err)
+	}
+	return err
+}
+
+// renewSession re-authenticates the client while holding the session exclusively, and reports if
+// it attempted to, i.e. if the client had not been re-authenticated since gen was acquired.
+func (client *Client) renewSession(gen uint64) (bool, error) {
+	client.session.mu.Lock()
+	defer client.session.mu.Unlock()
+	if client.session.closed.Load() {
+		return false, ErrClientClosed
+	}
+	if client.session.generation != gen {
+		return false, nil
+	}
+	err := client.opts.authenticate(client.VCD)
+	if err == nil {
+		client.session.generation++
+	}
+	client.log(context.Background(), "re-authenticated with vCloud after session expired", errAttr(err))
+	return true, err
+}
@@ -0,0 +1,153 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// StorageProfileUsage is the storage usage of a single VDC storage profile.
+type StorageProfileUsage struct {
+	ID      string
+	Name    string
+	Default bool
+	Used    DataStorage
+	Limit   DataStorage
+}
+
+// UsageSnapshot is a point-in-time view of the usage of a single VDC, from which all usage metrics
+// of the VDC can be derived without further requests to vCloud.
+type UsageSnapshot struct {
+	VDCID            string
+	VDCName          string
+	OrgID            string
+	OrgName          string
+	Speed            uint64
+	Cores            uint64
+	Memory           DataStorage
+	Storage          DataStorage
+	StorageAll       DataStorage
+	StorageProfiles  []StorageProfileUsage
+	VMCount          uint64
+	PoweredOnVMCount uint64
+	// CoresUnavailable is set if the VDC does not report a vCPU speed, for example a Flex or
+	// allocation pool VDC, so Speed and Cores are zero.
+	CoresUnavailable bool
+	// StorageUnavailable is set if the VDC does not have exactly one default storage profile, so
+	// Storage is zero.
+	StorageUnavailable bool
+	// StartedAt is the time collection of the snapshot started.
+	StartedAt time.Time
+	// CollectedAt is the time collection of the snapshot completed.
+	CollectedAt time.Time
+	// deployed holds the VMs deployed in a vApp, used to evaluate VM queries.
+	deployed []*types.QueryResultVMRecordType
+}
+
+// VMCountWithQuery returns the number of VMs in the snapshot matching all of the provided queries.
+// If PoweredOn is false (default), VMs that are both powered on or off will be included.
+func (snap *UsageSnapshot) VMCountWithQuery(queries ...VMQuerySetter) uint64 {
+	return uint64(len(filterVMs(snap.deployed, queries...)))
+}
+
+// VMCoreCountWithQuery returns the number of cores on VMs in the snapshot matching all of the
+// provided queries. If PoweredOn is false (default), VMs that are both powered on or off will be
+// included.
+func (snap *UsageSnapshot) VMCoreCountWithQuery(queries ...VMQuerySetter) uint64 {
+	return coresOfVMs(filterVMs(snap.deployed, queries...))
+}
+
+// Snapshot retrieves the usage of a VDC, fetching the admin VDC, its storage profiles, and its VMs
+// only once.
+func (vdc *VDC) Snapshot() (*UsageSnapshot, error) {
+	return vdc.SnapshotContext(context.Background())
+}
+
+// SnapshotContext is the same as Snapshot, but aborts if ctx is cancelled.
+func (vdc *VDC) SnapshotContext(ctx context.Context) (_ *UsageSnapshot, err error) {
+	ctx, span := vdc.startSpan(ctx, "Snapshot")
+	defer func() { endSpan(span, err) }()
+	started := time.Now()
+	avdc, err := vdc.adminVDC(ctx)
+	if err != nil {
+		return nil, err
+	}
+	profiles, err := vdc.storageProfiles(ctx, avdc)
+	if err != nil {
+		return nil, err
+	}
+	vms, err := vdc.vmList(ctx, types.VmQueryFilterAll)
+	if err != nil {
+		return nil, err
+	}
+	snap := usageOf(avdc, profiles, vms)
+	snap.VDCID = vdc.Obj.Vdc.ID
+	snap.VDCName = vdc.Obj.Vdc.Name
+	snap.OrgID = vdc.AdminOrg.AdminOrg.ID
+	snap.OrgName = vdc.AdminOrg.AdminOrg.Name
+	snap.StartedAt = started
+	snap.CollectedAt = time.Now()
+	return snap, nil
+}
+
+// usageOf calculates the usage of a VDC from its admin VDC, storage profiles, and VMs. The
+// snapshot does not identify the VDC or its organization. If the cores or default storage of the
+// VDC cannot be determined, they are marked unavailable rather than failing the snapshot.
+func usageOf(avdc *govcd.AdminVdc, profiles []*types.VdcStorageProfile, vms []*types.QueryResultVMRecordType) *UsageSnapshot {
+	deployed := make([]*types.QueryResultVMRecordType, 0, len(vms))
+	for _, vm := range vms {
+		if !vm.VAppTemplate {
+			deployed = append(deployed, vm)
+		}
+	}
+	usage := make([]StorageProfileUsage, 0, len(profiles))
+	for _, sp := range profiles {
+		usage = append(usage, StorageProfileUsage{
+			ID:      sp.ID,
+			Name:    sp.Name,
+			Default: sp.Default,
+			Used:    storageOf(sp),
+			Limit:   DataStorage(bytesOf(sp.Limit, sp.Units)),
+		})
+	}
+	snap := &UsageSnapshot{
+		Memory:           memoryOf(avdc),
+		StorageAll:       storageOf(profiles...),
+		StorageProfiles:  usage,
+		VMCount:          countVMs(vms),
+		PoweredOnVMCount: countPoweredOnVMs(vms),
+		deployed:         deployed,
+	}
+	if speed, err := speedOf(avdc); err == nil {
+		snap.Speed = speed
+		snap.Cores, _ = coresOf(avdc)
+	} else {
+		snap.CoresUnavailable = true
+	}
+	if def, err := defaultOf(profiles); err == nil {
+		snap.Storage = storageOf(def)
+	} else {
+		snap.StorageUnavailable = true
+	}
+	return snap
+}
+
+// Snapshot retrieves the usage of every VDC. See VDC.Snapshot.
+func (vdcs VDCs) Snapshot() ([]*UsageSnapshot, error) {
+	return vdcs.SnapshotContext(context.Background())
+}
+
+// SnapshotContext is the same as Snapshot, but aborts if ctx is cancelled. If the usage cannot be
+// retrieved for any VDC, an error identifying each failed VDC is returned.
+func (vdcs VDCs) SnapshotContext(ctx context.Context) (_ []*UsageSnapshot, err error) {
+	ctx, span := vdcs.startSpan(ctx, "Snapshot")
+	defer func() { endSpan(span, err) }()
+	snaps, errs := each(ctx, vdcs, (*VDC).SnapshotContext)
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return snaps, nil
+}
@@ -0,0 +1,58 @@
+package vcdusage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+func Test_usageOf(t *testing.T) {
+	speed := int64(2000)
+	newAdminVDC := func(speed *int64) *govcd.AdminVdc {
+		return &govcd.AdminVdc{AdminVdc: &types.AdminVdc{
+			Vdc: types.Vdc{ComputeCapacity: []*types.ComputeCapacity{{
+				CPU:    &types.CapacityWithUsage{Units: "MHz", Used: 8000},
+				Memory: &types.CapacityWithUsage{Units: "MB", Used: 4096},
+			}}},
+			VCpuInMhz2: speed,
+		}}
+	}
+	profiles := []*types.VdcStorageProfile{
+		{Name: "fast", Default: true, StorageUsedMB: 1024},
+		{Name: "slow", StorageUsedMB: 512},
+	}
+	vms := []*types.QueryResultVMRecordType{
+		{Name: "web-1", Status: "POWERED_ON", Cpus: 2},
+		{Name: "web-2", Status: "POWERED_OFF", Cpus: 2},
+	}
+	t.Run("complete", func(t *testing.T) {
+		t.Parallel()
+		snap := usageOf(newAdminVDC(&speed), profiles, vms)
+		assert.False(t, snap.CoresUnavailable)
+		assert.False(t, snap.StorageUnavailable)
+		assert.Equal(t, uint64(2000), snap.Speed)
+		assert.Equal(t, uint64(4), snap.Cores)
+		assert.Equal(t, DataStorage(1024*mb), snap.Storage)
+	})
+	t.Run("no cpu speed", func(t *testing.T) {
+		t.Parallel()
+		snap := usageOf(newAdminVDC(nil), profiles, vms)
+		assert.True(t, snap.CoresUnavailable)
+		assert.Zero(t, snap.Speed)
+		assert.Zero(t, snap.Cores)
+		assert.Equal(t, DataStorage(4096*mb), snap.Memory)
+		assert.Equal(t, DataStorage(1024*mb), snap.Storage)
+		assert.Equal(t, uint64(2), snap.VMCount)
+		assert.Equal(t, uint64(1), snap.PoweredOnVMCount)
+	})
+	t.Run("no default storage profile", func(t *testing.T) {
+		t.Parallel()
+		snap := usageOf(newAdminVDC(&speed), profiles[1:], vms)
+		assert.True(t, snap.StorageUnavailable)
+		assert.Zero(t, snap.Storage)
+		assert.Equal(t, DataStorage(512*mb), snap.StorageAll)
+		assert.Equal(t, uint64(4), snap.Cores)
+	})
+}
@@ -0,0 +1,36 @@
+package vcdusage_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.stellar.af/go-vcdusage"
+)
+
+func Test_Snapshot(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	vdc, err := client.VDC(Env.OrgID, Env.VdcID)
+	require.NoError(t, err)
+	snap, err := vdc.Snapshot()
+	require.NoError(t, err)
+	assert.Equal(t, Env.Cores, snap.Cores, "mismatching core count: %v != %v", Env.Cores, snap.Cores)
+	assert.Equal(t, Env.Memory, snap.Memory.GB(), "mismatching memory: %v != %v", Env.Memory, snap.Memory.GB())
+	assert.Equal(t, Env.Storage, snap.Storage.GB(), "mismatching storage: %v != %v", Env.Storage, snap.Storage.GB())
+	total := Env.VMCountOn + Env.VMCountOff
+	assert.Equal(t, total, snap.VMCount, "mismatching VM count: %v != %v", total, snap.VMCount)
+	assert.Equal(t, Env.VMCountOn, snap.PoweredOnVMCount, "mismatching powered-on VM count: %v != %v", Env.VMCountOn, snap.PoweredOnVMCount)
+	assert.Equal(t, vdc.StorageAll(), snap.StorageAll, "mismatching total storage")
+	assert.NotEmpty(t, snap.StorageProfiles, "no storage profiles")
+	assert.False(t, snap.CollectedAt.Before(snap.StartedAt), "collected before started")
+	count := snap.VMCountWithQuery(vcdusage.VMWithGuestOSContaining("windows"))
+	assert.Equal(t, Env.WindowsCount, count, "mismatching Windows VM count: %v != %v", Env.WindowsCount, count)
+}
@@ -0,0 +1,145 @@
+package vcdusage
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/joomcode/errorx"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	metricnoop "go.opentelemetry.io/otel/metric/noop"
+	"go.opentelemetry.io/otel/trace"
+	tracenoop "go.opentelemetry.io/otel/trace/noop"
+)
+
+// instrumentationName identifies the spans and metrics recorded by this package.
+const instrumentationName = "go.stellar.af/go-vcdusage"
+
+// TracerProvider records spans around Client and VDC methods and each underlying govcd operation
+// with tp. If not set, no spans are recorded.
+func TracerProvider(tp trace.TracerProvider) Option {
+	return func(opts *Options) {
+		opts.TracerProvider = tp
+	}
+}
+
+// MeterProvider records the duration and errors of each govcd operation with mp, as the
+// vcdusage.operation.duration histogram and the vcdusage.operation.errors counter. If not set, no
+// metrics are recorded.
+func MeterProvider(mp metric.MeterProvider) Option {
+	return func(opts *Options) {
+		opts.MeterProvider = mp
+	}
+}
+
+// telemetry holds the instruments used to trace and measure a client.
+type telemetry struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	errors   metric.Int64Counter
+}
+
+// noopTelemetry is used by clients without telemetry, e.g. clients not created with New.
+var noopTelemetry = func() *telemetry {
+	tel, _ := newTelemetry(tracenoop.NewTracerProvider(), metricnoop.NewMeterProvider())
+	return tel
+}()
+
+// newTelemetry creates the instruments of a client from its providers.
+func newTelemetry(tp trace.TracerProvider, mp metric.MeterProvider) (*telemetry, error) {
+	meter := mp.Meter(instrumentationName)
+	duration, err := meter.Float64Histogram(
+		"vcdusage.operation.duration",
+		metric.WithDescription("Duration of govcd operations, including retries."),
+		metric.WithUnit("s"),
+	)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to create operation duration histogram")
+		return nil, err
+	}
+	failures, err := meter.Int64Counter(
+		"vcdusage.operation.errors",
+		metric.WithDescription("Number of govcd operations that failed."),
+		metric.WithUnit("{error}"),
+	)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to create operation error counter")
+		return nil, err
+	}
+	tel := &telemetry{
+		tracer:   tp.Tracer(instrumentationName),
+		duration: duration,
+		errors:   failures,
+	}
+	return tel, nil
+}
+
+// telemetry creates the instruments of the client from its options.
+func (opts *Options) telemetry() (*telemetry, error) {
+	var tp trace.TracerProvider = tracenoop.NewTracerProvider()
+	if opts.TracerProvider != nil {
+		tp = opts.TracerProvider
+	}
+	var mp metric.MeterProvider = metricnoop.NewMeterProvider()
+	if opts.MeterProvider != nil {
+		mp = opts.MeterProvider
+	}
+	return newTelemetry(tp, mp)
+}
+
+// tel returns the instruments of the client.
+func (client *Client) tel() *telemetry {
+	if client == nil || client.telemetry == nil {
+		return noopTelemetry
+	}
+	return client.telemetry
+}
+
+// spanAttrs converts log attributes to span attributes.
+func spanAttrs(attrs []slog.Attr) []attribute.KeyValue {
+	kvs := make([]attribute.KeyValue, 0, len(attrs))
+	for _, attr := range attrs {
+		if attr.Equal(slog.Attr{}) {
+			continue
+		}
+		key := "vcd." + attr.Key
+		switch attr.Value.Kind() {
+		case slog.KindInt64:
+			kvs = append(kvs, attribute.Int64(key, attr.Value.Int64()))
+		case slog.KindBool:
+			kvs = append(kvs, attribute.Bool(key, attr.Value.Bool()))
+		default:
+			kvs = append(kvs, attribute.String(key, attr.Value.String()))
+		}
+	}
+	return kvs
+}
+
+// startSpan starts a span named name as a child of any span in ctx.
+func (client *Client) startSpan(ctx context.Context, name string, attrs ...slog.Attr) (context.Context, trace.Span) {
+	return client.tel().tracer.Start(ctx, name, trace.WithAttributes(spanAttrs(attrs)...))
+}
+
+// startSpan starts a span for a VDC method as a child of any span in ctx.
+func (vdc *VDC) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return vdc.Client.startSpan(ctx, "VDC."+method, vdc.logAttrs()...)
+}
+
+// startSpan starts a span for a VDCs method as a child of any span in ctx.
+func (vdcs VDCs) startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	var client *Client
+	if len(vdcs) != 0 {
+		client = vdcs[0].Client
+	}
+	return client.startSpan(ctx, "VDCs."+method, slog.Int("vdcs", len(vdcs)))
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
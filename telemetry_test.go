@@ -0,0 +1,92 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_telemetry(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	tel, err := newTelemetry(tp, mp)
+	require.NoError(t, err)
+	vdcs := testVDCs(4, 2)
+	vdcs[0].Client.telemetry = tel
+	errFailed := errors.New("API Error: 403: Forbidden")
+	get := func(vdc *VDC, ctx context.Context) (uint64, error) {
+		ctx, span := vdc.startSpan(ctx, "CoreCount")
+		value, err := call(ctx, vdc.Client, "GetAdminVDCById", func() (uint64, error) {
+			if vdc.Obj.Vdc.ID == "vdc-3" {
+				return 0, errFailed
+			}
+			return 1, nil
+		}, vdc.logAttrs()...)
+		endSpan(span, err)
+		return value, err
+	}
+	ctx, span := vdcs.startSpan(context.Background(), "CoreCount")
+	_, err = collect(ctx, vdcs, get, sum[uint64])
+	endSpan(span, err)
+	require.Error(t, err)
+
+	t.Run("spans", func(t *testing.T) {
+		spans := exporter.GetSpans()
+		require.Len(t, spans, 9)
+		byName := make(map[string][]tracetest.SpanStub)
+		for _, s := range spans {
+			byName[s.Name] = append(byName[s.Name], s)
+		}
+		require.Len(t, byName["VDCs.CoreCount"], 1)
+		root := byName["VDCs.CoreCount"][0]
+		assert.Equal(t, codes.Error, root.Status.Code)
+		assert.Contains(t, root.Attributes, attribute.Int64("vcd.vdcs", 4))
+		require.Len(t, byName["VDC.CoreCount"], 4)
+		for _, s := range byName["VDC.CoreCount"] {
+			assert.Equal(t, root.SpanContext.SpanID(), s.Parent.SpanID())
+		}
+		require.Len(t, byName["govcd.GetAdminVDCById"], 4)
+		failed := 0
+		for _, s := range byName["govcd.GetAdminVDCById"] {
+			assert.Contains(t, s.Attributes, attribute.String("vcd.operation", "GetAdminVDCById"))
+			if s.Status.Code == codes.Error {
+				failed++
+				assert.Contains(t, s.Attributes, attribute.String("vcd.vdc_id", "vdc-3"))
+			}
+		}
+		assert.Equal(t, 1, failed)
+	})
+	t.Run("metrics", func(t *testing.T) {
+		var rm metricdata.ResourceMetrics
+		require.NoError(t, reader.Collect(context.Background(), &rm))
+		require.Len(t, rm.ScopeMetrics, 1)
+		metrics := make(map[string]metricdata.Metrics)
+		for _, m := range rm.ScopeMetrics[0].Metrics {
+			metrics[m.Name] = m
+		}
+		duration, ok := metrics["vcdusage.operation.duration"].Data.(metricdata.Histogram[float64])
+		require.True(t, ok)
+		require.Len(t, duration.DataPoints, 1)
+		assert.Equal(t, uint64(4), duration.DataPoints[0].Count)
+		failures, ok := metrics["vcdusage.operation.errors"].Data.(metricdata.Sum[int64])
+		require.True(t, ok)
+		require.Len(t, failures.DataPoints, 1)
+		assert.Equal(t, int64(1), failures.DataPoints[0].Value)
+	})
+	t.Run("no-op", func(t *testing.T) {
+		client := &Client{opts: &Options{}}
+		_, err := call(context.Background(), client, "GetOrgList", func() (int, error) { return 1, nil })
+		require.NoError(t, err)
+	})
+}
@@ -0,0 +1,151 @@
+package vcdusage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/joomcode/errorx"
+)
+
+// ErrCfgFingerprint indicates a pinned certificate fingerprint is not a valid SHA-256 fingerprint.
+var ErrCfgFingerprint = errors.New("certificate fingerprint must be a hex-encoded SHA-256 hash")
+
+// ErrCfgTLSVersion indicates the minimum TLS version is not a known TLS version.
+var ErrCfgTLSVersion = errors.New("unknown minimum TLS version")
+
+// ErrCfgCACert indicates a CA bundle does not contain any PEM-encoded certificates.
+var ErrCfgCACert = errors.New("CA bundle contains no certificates")
+
+// ErrCertNotPinned indicates the certificate presented by vCloud does not match any pinned
+// fingerprint.
+var ErrCertNotPinned = errors.New("server certificate does not match any pinned fingerprint")
+
+// CACert trusts the PEM-encoded CA certificates in bundle, in addition to the system's trusted CA
+// certificates, when verifying the vCloud server certificate.
+func CACert(bundle []byte) Option {
+	return func(opts *Options) {
+		opts.CACerts = append(opts.CACerts, bundle)
+	}
+}
+
+// CACertFile is the same as CACert, but reads the CA bundle from file.
+func CACertFile(file string) Option {
+	return func(opts *Options) {
+		bundle, err := os.ReadFile(file)
+		if err != nil {
+			opts.err = errorx.Decorate(err, "failed to read CA bundle '%s'", file)
+			return
+		}
+		opts.CACerts = append(opts.CACerts, bundle)
+	}
+}
+
+// PinCertificate requires the vCloud server certificate to match the hex-encoded SHA-256
+// fingerprint, with or without colon separators. It may be set more than once to allow several
+// certificates, e.g. while a certificate is being replaced. The certificate chain is still
+// verified unless Insecure is also set, in which case the fingerprint alone is trusted.
+func PinCertificate(fingerprint string) Option {
+	return func(opts *Options) {
+		opts.CertFingerprints = append(opts.CertFingerprints, fingerprint)
+	}
+}
+
+// ClientCertificate presents cert to vCloud for mutual TLS authentication.
+func ClientCertificate(cert tls.Certificate) Option {
+	return func(opts *Options) {
+		opts.ClientCert = &cert
+	}
+}
+
+// ClientCertificateFile is the same as ClientCertificate, but reads a PEM-encoded certificate and
+// private key from files.
+func ClientCertificateFile(certFile, keyFile string) Option {
+	return func(opts *Options) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			opts.err = errorx.Decorate(err, "failed to load client certificate '%s'", certFile)
+			return
+		}
+		opts.ClientCert = &cert
+	}
+}
+
+// MinTLSVersion sets the minimum TLS version accepted from vCloud, e.g. tls.VersionTLS13. If not
+// set, the default of crypto/tls is used.
+func MinTLSVersion(version uint16) Option {
+	return func(opts *Options) {
+		opts.MinTLSVersion = version
+	}
+}
+
+// validTLSVersion determines if version is a TLS version known to crypto/tls.
+func validTLSVersion(version uint16) bool {
+	switch version {
+	case tls.VersionTLS10, tls.VersionTLS11, tls.VersionTLS12, tls.VersionTLS13:
+		return true
+	}
+	return false
+}
+
+// parseFingerprint decodes a hex-encoded SHA-256 fingerprint, with or without colon separators.
+func parseFingerprint(fingerprint string) ([]byte, error) {
+	hash, err := hex.DecodeString(strings.ReplaceAll(fingerprint, ":", ""))
+	if err != nil || len(hash) != sha256.Size {
+		return nil, fmt.Errorf("%w: '%s'", ErrCfgFingerprint, fingerprint)
+	}
+	return hash, nil
+}
+
+// tlsConfig creates the TLS configuration used to communicate with vCloud.
+func (opts *Options) tlsConfig() (*tls.Config, error) {
+	// #nosec G402 -- InsecureSkipVerify is only set by the Insecure option.
+	cfg := &tls.Config{
+		InsecureSkipVerify: opts.Insecure,
+		MinVersion:         opts.MinTLSVersion,
+	}
+	if len(opts.CACerts) != 0 {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		for _, bundle := range opts.CACerts {
+			if !pool.AppendCertsFromPEM(bundle) {
+				return nil, ErrCfgCACert
+			}
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.ClientCert != nil {
+		cfg.Certificates = []tls.Certificate{*opts.ClientCert}
+	}
+	if len(opts.CertFingerprints) != 0 {
+		pins := make([][]byte, 0, len(opts.CertFingerprints))
+		for _, fingerprint := range opts.CertFingerprints {
+			hash, err := parseFingerprint(fingerprint)
+			if err != nil {
+				return nil, err
+			}
+			pins = append(pins, hash)
+		}
+		cfg.VerifyConnection = func(state tls.ConnectionState) error {
+			if len(state.PeerCertificates) == 0 {
+				return ErrCertNotPinned
+			}
+			hash := sha256.Sum256(state.PeerCertificates[0].Raw)
+			for _, pin := range pins {
+				if bytes.Equal(hash[:], pin) {
+					return nil
+				}
+			}
+			return ErrCertNotPinned
+		}
+	}
+	return cfg, nil
+}
@@ -0,0 +1,71 @@
+package vcdusage
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_tlsConfig(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	cert := server.Certificate()
+	hash := sha256.Sum256(cert.Raw)
+	fingerprint := hex.EncodeToString(hash[:])
+	get := func(t *testing.T, opts *Options) error {
+		t.Helper()
+		cfg, err := opts.tlsConfig()
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+		res, err := client.Get(server.URL)
+		if err == nil {
+			res.Body.Close()
+		}
+		return err
+	}
+	t.Run("CA bundle", func(t *testing.T) {
+		t.Parallel()
+		bundle := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		assert.NoError(t, get(t, &Options{CACerts: [][]byte{bundle}}))
+	})
+	t.Run("untrusted", func(t *testing.T) {
+		t.Parallel()
+		assert.Error(t, get(t, &Options{}))
+	})
+	t.Run("pinned", func(t *testing.T) {
+		t.Parallel()
+		assert.NoError(t, get(t, &Options{Insecure: true, CertFingerprints: []string{fingerprint}}))
+	})
+	t.Run("pinned with colons", func(t *testing.T) {
+		t.Parallel()
+		colons := make([]byte, 0, len(fingerprint)*3/2)
+		for i := 0; i < len(fingerprint); i += 2 {
+			if i > 0 {
+				colons = append(colons, ':')
+			}
+			colons = append(colons, fingerprint[i:i+2]...)
+		}
+		assert.NoError(t, get(t, &Options{Insecure: true, CertFingerprints: []string{string(colons)}}))
+	})
+	t.Run("not pinned", func(t *testing.T) {
+		t.Parallel()
+		other := sha256.Sum256([]byte("other"))
+		err := get(t, &Options{Insecure: true, CertFingerprints: []string{hex.EncodeToString(other[:])}})
+		assert.ErrorIs(t, err, ErrCertNotPinned)
+	})
+	t.Run("min version", func(t *testing.T) {
+		t.Parallel()
+		cfg, err := (&Options{MinTLSVersion: tls.VersionTLS13}).tlsConfig()
+		require.NoError(t, err)
+		assert.Equal(t, uint16(tls.VersionTLS13), cfg.MinVersion)
+	})
+}
@@ -0,0 +1,117 @@
+package vcdusage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+// ErrCfgTransportConflict indicates a custom transport was set along with options that only apply
+// to the default transport.
+var ErrCfgTransportConflict = errors.New("TLS, proxy, and connection options cannot be used with a custom transport")
+
+// Timeout sets the maximum duration of a single HTTP request to vCloud, including reading the
+// response. If not set, the govcd default of 600 seconds is used.
+func Timeout(d time.Duration) Option {
+	return func(opts *Options) {
+		opts.Timeout = d
+	}
+}
+
+// Proxy routes requests to vCloud through the HTTP proxy at u. If not set, the proxy is read from
+// the HTTPS_PROXY and NO_PROXY environment variables.
+func Proxy(u *url.URL) Option {
+	return func(opts *Options) {
+		opts.Proxy = u
+	}
+}
+
+// MaxIdleConns sets the maximum number of idle connections to vCloud kept open for reuse. It should
+// be at least the Concurrency option, so that concurrent requests are not forced to open new
+// connections.
+func MaxIdleConns(n int) Option {
+	return func(opts *Options) {
+		opts.MaxIdleConns = n
+	}
+}
+
+// UserAgent sets the User-Agent header sent to vCloud.
+func UserAgent(ua string) Option {
+	return func(opts *Options) {
+		opts.UserAgent = ua
+	}
+}
+
+// Transport replaces the HTTP transport used to communicate with vCloud. The transport is used as
+// is, so it cannot be combined with the Insecure, TLS, Proxy, or MaxIdleConns options.
+func Transport(rt http.RoundTripper) Option {
+	return func(opts *Options) {
+		opts.Transport = rt
+	}
+}
+
+// Middleware wraps the HTTP transport used to communicate with vCloud, e.g. to add
+// instrumentation. It may be set more than once; each middleware wraps those set before it.
+func Middleware(mw func(http.RoundTripper) http.RoundTripper) Option {
+	return func(opts *Options) {
+		opts.Middleware = append(opts.Middleware, mw)
+	}
+}
+
+// usesDefaultTransport determines if any option that configures the default transport is set.
+func (opts *Options) usesDefaultTransport() bool {
+	return opts.Insecure ||
+		len(opts.CACerts) != 0 ||
+		len(opts.CertFingerprints) != 0 ||
+		opts.ClientCert != nil ||
+		opts.MinTLSVersion != 0 ||
+		opts.Proxy != nil ||
+		opts.MaxIdleConns != 0
+}
+
+// configureHTTP applies the HTTP options to the HTTP client of vcd.
+func (opts *Options) configureHTTP(vcd *govcd.VCDClient) error {
+	rt := opts.Transport
+	if rt == nil {
+		transport, ok := vcd.Client.Http.Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("unexpected HTTP transport type %T", vcd.Client.Http.Transport)
+		}
+		tlsConfig, err := opts.tlsConfig()
+		if err != nil {
+			err = errorx.Decorate(err, "invalid TLS configuration")
+			return err
+		}
+		transport.TLSClientConfig = tlsConfig
+		if opts.Proxy != nil {
+			transport.Proxy = http.ProxyURL(opts.Proxy)
+		}
+		if opts.MaxIdleConns > 0 {
+			transport.MaxIdleConns = opts.MaxIdleConns
+			transport.MaxIdleConnsPerHost = opts.MaxIdleConns
+		}
+		rt = transport
+	}
+	for _, mw := range opts.Middleware {
+		rt = mw(rt)
+	}
+	if opts.Logger != nil {
+		rt = &loggedTransport{opts: opts, next: rt}
+	}
+	if opts.RateLimiter != nil {
+		rt = &rateLimited{limiter: opts.RateLimiter, next: rt}
+	}
+	vcd.Client.Http.Transport = rt
+	if opts.Timeout > 0 {
+		vcd.Client.Http.Timeout = opts.Timeout
+	}
+	if opts.UserAgent != "" {
+		vcd.Client.UserAgent = opts.UserAgent
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package vcdusage
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"golang.org/x/time/rate"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return fn(req)
+}
+
+func Test_configureHTTP(t *testing.T) {
+	u, err := ParseURL("vcd.example.com")
+	require.NoError(t, err)
+	t.Run("defaults", func(t *testing.T) {
+		t.Parallel()
+		vcd := govcd.NewVCDClient(*u, false)
+		timeout := vcd.Client.Http.Timeout
+		require.NoError(t, (&Options{}).configureHTTP(vcd))
+		assert.Equal(t, timeout, vcd.Client.Http.Timeout)
+		assert.IsType(t, &http.Transport{}, vcd.Client.Http.Transport)
+	})
+	t.Run("client", func(t *testing.T) {
+		t.Parallel()
+		vcd := govcd.NewVCDClient(*u, false)
+		opts := &Options{Timeout: 30 * time.Second, UserAgent: "collector/1.0", MaxIdleConns: 16}
+		require.NoError(t, opts.configureHTTP(vcd))
+		assert.Equal(t, 30*time.Second, vcd.Client.Http.Timeout)
+		assert.Equal(t, "collector/1.0", vcd.Client.UserAgent)
+		transport := vcd.Client.Http.Transport.(*http.Transport)
+		assert.Equal(t, 16, transport.MaxIdleConnsPerHost)
+	})
+	t.Run("proxy", func(t *testing.T) {
+		t.Parallel()
+		proxied := make(chan string, 1)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied <- r.Host
+			w.WriteHeader(http.StatusOK)
+		}))
+		t.Cleanup(proxy.Close)
+		proxyURL, err := url.Parse(proxy.URL)
+		require.NoError(t, err)
+		vcd := govcd.NewVCDClient(*u, false)
+		require.NoError(t, (&Options{Proxy: proxyURL}).configureHTTP(vcd))
+		res, err := vcd.Client.Http.Get("http://vcd.example.com/api/versions")
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, "vcd.example.com", <-proxied)
+	})
+	t.Run("transport and middleware", func(t *testing.T) {
+		t.Parallel()
+		calls := make([]string, 0)
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			calls = append(calls, "transport")
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		})
+		middleware := func(name string) func(http.RoundTripper) http.RoundTripper {
+			return func(next http.RoundTripper) http.RoundTripper {
+				return roundTripFunc(func(req *http.Request) (*http.Response, error) {
+					calls = append(calls, name)
+					return next.RoundTrip(req)
+				})
+			}
+		}
+		opts := &Options{
+			Transport:  transport,
+			Middleware: []func(http.RoundTripper) http.RoundTripper{middleware("first"), middleware("second")},
+		}
+		vcd := govcd.NewVCDClient(*u, false)
+		require.NoError(t, opts.configureHTTP(vcd))
+		res, err := vcd.Client.Http.Get(u.String())
+		require.NoError(t, err)
+		res.Body.Close()
+		assert.Equal(t, []string{"second", "first", "transport"}, calls)
+	})
+	t.Run("rate limit", func(t *testing.T) {
+		t.Parallel()
+		var count atomic.Int64
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			count.Add(1)
+			return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody, Request: req}, nil
+		})
+		opts := &Options{Transport: transport, RateLimiter: rate.NewLimiter(rate.Every(20*time.Millisecond), 1)}
+		vcd := govcd.NewVCDClient(*u, false)
+		require.NoError(t, opts.configureHTTP(vcd))
+		started := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res, err := vcd.Client.Http.Get(u.String())
+				if assert.NoError(t, err) {
+					res.Body.Close()
+				}
+			}()
+		}
+		wg.Wait()
+		assert.Equal(t, int64(6), count.Load())
+		assert.GreaterOrEqual(t, time.Since(started), 100*time.Millisecond)
+	})
+}
@@ -1,10 +1,12 @@
 package vcdusage
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"strings"
 
-	"github.com/destel/rill"
 	"github.com/joomcode/errorx"
 	"github.com/vmware/go-vcloud-director/v2/govcd"
 	"github.com/vmware/go-vcloud-director/v2/types/v56"
@@ -22,6 +24,9 @@ func isValidStatus(status string) bool {
 
 // VDC is a wrapper around a govcd.Vdc object with references to the corresponding Admin Org and
 // vcdusage.Client.
+//
+// Usage methods without a Context suffix report zero when usage cannot be retrieved; use the
+// corresponding Context method to receive the error instead.
 type VDC struct {
 	Obj      *govcd.Vdc
 	AdminOrg *govcd.AdminOrg
@@ -29,114 +34,235 @@ type VDC struct {
 }
 
 // VDCs is a slice of vcdusage.VDC wrappers.
+//
+// Totals from methods without a Context suffix are lossy: a VDC whose usage cannot be retrieved is
+// left out of the total and the error is only logged, so the total silently under-reports usage,
+// and is zero if every VDC fails. Use the corresponding Context method, which returns an error
+// identifying each failed VDC, wherever an incomplete total would be wrong.
 type VDCs []VDC
 
+// ErrNoCPUSpeed indicates a VDC does not report a vCPU speed, so its core count cannot be
+// calculated.
+var ErrNoCPUSpeed = errors.New("VDC has no vCPU speed")
+
+// VDCError wraps an error encountered while retrieving usage for a single VDC.
+type VDCError struct {
+	ID    string
+	Name  string
+	OrgID string
+	Err   error
+}
+
+// Error implements the error interface.
+func (e *VDCError) Error() string {
+	return fmt.Sprintf("VDC '%s' (%s): %s", e.Name, e.ID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *VDCError) Unwrap() error {
+	return e.Err
+}
+
+// wrapError wraps err in a VDCError identifying the VDC.
+func (vdc *VDC) wrapError(err error) error {
+	vdcErr := &VDCError{ID: vdc.Obj.Vdc.ID, Name: vdc.Obj.Vdc.Name, Err: err}
+	if vdc.AdminOrg != nil && vdc.AdminOrg.AdminOrg != nil {
+		vdcErr.OrgID = vdc.AdminOrg.AdminOrg.ID
+	}
+	return vdcErr
+}
+
 // CoreCount retrieves the used CPU MHz for a VDC and calculates the number of cores used
 // by all VDCs by dividing the total used CPU MHz by the CPU speed.
 //
 // For example, if the speed is 3.1 GHz and the used amount is 49.6, the core count is 16.
+//
+// The count is lossy: VDCs that fail are left out. Use CoreCountContext to detect failures.
 func (vdcs VDCs) CoreCount() uint64 {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	count := uint64(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		count += vdc.CoreCount()
-		return nil
-	})
+	count, errs := reduce(context.Background(), vdcs, withCPUSpeed("CoreCount", (*VDC).CoreCountContext), sum[uint64])
+	vdcs.swallowed("CoreCount", errs)
 	return count
 }
 
 // Memory retrieves the amount of used memory to all VDCs, represented as a DataStorage type.
+// The total is lossy: VDCs that fail are left out. Use MemoryContext to detect failures.
 func (vdcs VDCs) Memory() DataStorage {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	mem := DataStorage(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		mem += vdc.Memory()
-		return nil
-	})
+	mem, errs := reduce(context.Background(), vdcs, (*VDC).MemoryContext, sum[DataStorage])
+	vdcs.swallowed("Memory", errs)
 	return mem
 }
 
-// Memory retrieves the amount of used storage to all VDCs, represented as a DataStorage type.
+// Storage retrieves the amount of used storage to all VDCs, represented as a DataStorage type.
+// The total is lossy: VDCs that fail are left out. Use StorageContext to detect failures.
 func (vdcs VDCs) Storage() DataStorage {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	stor := DataStorage(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		stor += vdc.Storage()
-		return nil
-	})
+	stor, errs := reduce(context.Background(), vdcs, (*VDC).StorageContext, sum[DataStorage])
+	vdcs.swallowed("Storage", errs)
 	return stor
 }
 
-// VMCount retrieves the number of VMs deployed in all VDCs.
+// VMCount retrieves the number of VMs deployed in all VDCs. The count is lossy: VDCs that fail
+// are left out. Use VMCountContext to detect failures.
 func (vdcs VDCs) VMCount() uint64 {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	count := uint64(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		count += vdc.VMCount()
-		return nil
-	})
+	count, errs := reduce(context.Background(), vdcs, (*VDC).VMCountContext, sum[uint64])
+	vdcs.swallowed("VMCount", errs)
 	return count
 }
 
-// PoweredOnVMCount retrieves the number of powered on VMs deployed in all VDCs.
+// PoweredOnVMCount retrieves the number of powered on VMs deployed in all VDCs. The count is lossy:
+// VDCs that fail are left out. Use PoweredOnVMCountContext to detect failures.
 func (vdcs VDCs) PoweredOnVMCount() uint64 {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	count := uint64(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		count += vdc.PoweredOnVMCount()
-		return nil
-	})
+	count, errs := reduce(context.Background(), vdcs, (*VDC).PoweredOnVMCountContext, sum[uint64])
+	vdcs.swallowed("PoweredOnVMCount", errs)
 	return count
 }
 
 // VMCountWithQuery retrieves the number of VMs matching all of the provided queries in all VDCs.
-// If PoweredOn is false (default), VMs that are both powered on or off will be included.
+// If PoweredOn is false (default), VMs that are both powered on or off will be included. The count
+// is lossy: VDCs that fail are left out. Use VMCountWithQueryContext to detect failures.
 func (vdcs VDCs) VMCountWithQuery(queries ...VMQuerySetter) uint64 {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	count := uint64(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		count += vdc.VMCountWithQuery(queries...)
-		return nil
-	})
+	get := func(vdc *VDC, ctx context.Context) (uint64, error) {
+		return vdc.VMCountWithQueryContext(ctx, queries...)
+	}
+	count, errs := reduce(context.Background(), vdcs, get, sum[uint64])
+	vdcs.swallowed("VMCountWithQuery", errs)
 	return count
 }
 
-// VMCountWithQuery retrieves the number of cores on VMs matching all of the provided queries in
+// VMCoreCountWithQuery retrieves the number of cores on VMs matching all of the provided queries in
 // all VDCs. If PoweredOn is false (default), VMs that are both powered on or off will be included.
+// The count is lossy: VDCs that fail are left out. Use VMCoreCountWithQueryContext to detect
+// failures.
 func (vdcs VDCs) VMCoreCountWithQuery(queries ...VMQuerySetter) uint64 {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	count := uint64(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		count += vdc.VMCoreCountWithQuery(queries...)
-		return nil
-	})
+	get := func(vdc *VDC, ctx context.Context) (uint64, error) {
+		return vdc.VMCoreCountWithQueryContext(ctx, queries...)
+	}
+	count, errs := reduce(context.Background(), vdcs, get, sum[uint64])
+	vdcs.swallowed("VMCoreCountWithQuery", errs)
 	return count
 }
 
 // Speed retrieves the max CPU speed of all VDCs in MHz. This is required for calculating core count.
+// VDCs that fail are left out, so the speed may be lower than the actual maximum. Use SpeedContext
+// to detect failures.
 func (vdcs VDCs) Speed() uint64 {
-	vdcSlice := rill.FromSlice(vdcs, nil)
-	speed := uint64(0)
-	rill.ForEach(vdcSlice, len(vdcs), func(vdc VDC) error {
-		vdcSpeed := vdc.Speed()
-		if vdcSpeed > speed {
-			speed = vdcSpeed
-		}
-		return nil
-	})
+	speed, errs := reduce(context.Background(), vdcs, withCPUSpeed("Speed", (*VDC).SpeedContext), greatest[uint64])
+	vdcs.swallowed("Speed", errs)
 	return speed
 }
 
+// CoreCountContext is the same as CoreCount, but aborts if ctx is cancelled. If the core count
+// cannot be retrieved for any VDC, an error identifying each failed VDC is returned. VDCs that do
+// not report a vCPU speed are left out of the count and logged, as snapshots mark their cores
+// unavailable.
+func (vdcs VDCs) CoreCountContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdcs.startSpan(ctx, "CoreCount")
+	defer func() { endSpan(span, err) }()
+	return collect(ctx, vdcs, withCPUSpeed("CoreCount", (*VDC).CoreCountContext), sum[uint64])
+}
+
+// MemoryContext is the same as Memory, but aborts if ctx is cancelled. If memory cannot be
+// retrieved for any VDC, an error identifying each failed VDC is returned.
+func (vdcs VDCs) MemoryContext(ctx context.Context) (_ DataStorage, err error) {
+	ctx, span := vdcs.startSpan(ctx, "Memory")
+	defer func() { endSpan(span, err) }()
+	return collect(ctx, vdcs, (*VDC).MemoryContext, sum[DataStorage])
+}
+
+// StorageContext is the same as Storage, but aborts if ctx is cancelled. If storage cannot be
+// retrieved for any VDC, an error identifying each failed VDC is returned.
+func (vdcs VDCs) StorageContext(ctx context.Context) (_ DataStorage, err error) {
+	ctx, span := vdcs.startSpan(ctx, "Storage")
+	defer func() { endSpan(span, err) }()
+	return collect(ctx, vdcs, (*VDC).StorageContext, sum[DataStorage])
+}
+
+// VMCountContext is the same as VMCount, but aborts if ctx is cancelled. If the VM count cannot be
+// retrieved for any VDC, an error identifying each failed VDC is returned.
+func (vdcs VDCs) VMCountContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdcs.startSpan(ctx, "VMCount")
+	defer func() { endSpan(span, err) }()
+	return collect(ctx, vdcs, (*VDC).VMCountContext, sum[uint64])
+}
+
+// PoweredOnVMCountContext is the same as PoweredOnVMCount, but aborts if ctx is cancelled. If the
+// VM count cannot be retrieved for any VDC, an error identifying each failed VDC is returned.
+func (vdcs VDCs) PoweredOnVMCountContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdcs.startSpan(ctx, "PoweredOnVMCount")
+	defer func() { endSpan(span, err) }()
+	return collect(ctx, vdcs, (*VDC).PoweredOnVMCountContext, sum[uint64])
+}
+
+// VMCountWithQueryContext is the same as VMCountWithQuery, but aborts if ctx is cancelled. If the
+// VM count cannot be retrieved for any VDC, an error identifying each failed VDC is returned.
+func (vdcs VDCs) VMCountWithQueryContext(ctx context.Context, queries ...VMQuerySetter) (_ uint64, err error) {
+	ctx, span := vdcs.startSpan(ctx, "VMCountWithQuery")
+	defer func() { endSpan(span, err) }()
+	get := func(vdc *VDC, ctx context.Context) (uint64, error) {
+		return vdc.VMCountWithQueryContext(ctx, queries...)
+	}
+	return collect(ctx, vdcs, get, sum[uint64])
+}
+
+// VMCoreCountWithQueryContext is the same as VMCoreCountWithQuery, but aborts if ctx is cancelled.
+// If the core count cannot be retrieved for any VDC, an error identifying each failed VDC is
+// returned.
+func (vdcs VDCs) VMCoreCountWithQueryContext(ctx context.Context, queries ...VMQuerySetter) (_ uint64, err error) {
+	ctx, span := vdcs.startSpan(ctx, "VMCoreCountWithQuery")
+	defer func() { endSpan(span, err) }()
+	get := func(vdc *VDC, ctx context.Context) (uint64, error) {
+		return vdc.VMCoreCountWithQueryContext(ctx, queries...)
+	}
+	return collect(ctx, vdcs, get, sum[uint64])
+}
+
+// SpeedContext is the same as Speed, but aborts if ctx is cancelled. If the speed cannot be
+// retrieved for any VDC, an error identifying each failed VDC is returned. VDCs that do not report
+// a vCPU speed are left out and logged, the same as by CoreCountContext.
+func (vdcs VDCs) SpeedContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdcs.startSpan(ctx, "Speed")
+	defer func() { endSpan(span, err) }()
+	return collect(ctx, vdcs, withCPUSpeed("Speed", (*VDC).SpeedContext), greatest[uint64])
+}
+
+// withCPUSpeed wraps get, which retrieves a metric derived from the vCPU speed of a VDC, so that
+// VDCs that do not report a vCPU speed are logged and contribute nothing instead of failing.
+func withCPUSpeed(method string, get func(*VDC, context.Context) (uint64, error)) func(*VDC, context.Context) (uint64, error) {
+	return func(vdc *VDC, ctx context.Context) (uint64, error) {
+		value, err := get(vdc, ctx)
+		if errors.Is(err, ErrNoCPUSpeed) {
+			attrs := append(vdc.logAttrs(), slog.String("method", method))
+			vdc.Client.log(ctx, "excluding VDC without vCPU speed from total", attrs...)
+			return 0, nil
+		}
+		return value, err
+	}
+}
+
 // Speed retrieves the CPU speed of a VDC in MHz. This is required for calculating core count.
 func (vdc *VDC) Speed() uint64 {
-	avdc, err := vdc.AdminOrg.GetAdminVDCById(vdc.Obj.Vdc.ID, false)
+	speed, err := vdc.SpeedContext(context.Background())
+	vdc.swallowed("Speed", err)
+	return speed
+}
+
+// SpeedContext retrieves the CPU speed of a VDC in MHz, aborting if ctx is cancelled. If the VDC
+// does not report a vCPU speed, ErrNoCPUSpeed is returned.
+func (vdc *VDC) SpeedContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdc.startSpan(ctx, "Speed")
+	defer func() { endSpan(span, err) }()
+	avdc, err := vdc.adminVDC(ctx)
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	if avdc.AdminVdc.VCpuInMhz2 == nil {
-		return 0
+	return speedOf(avdc)
+}
+
+// speedOf returns the vCPU speed of an admin VDC in MHz.
+func speedOf(avdc *govcd.AdminVdc) (uint64, error) {
+	if avdc.AdminVdc.VCpuInMhz2 == nil || *avdc.AdminVdc.VCpuInMhz2 == 0 {
+		return 0, ErrNoCPUSpeed
 	}
-	return uint64(*avdc.AdminVdc.VCpuInMhz2)
+	return uint64(*avdc.AdminVdc.VCpuInMhz2), nil
 }
 
 // CoreCount retrieves the used CPU MHz for a VDC and calculates the number of cores used
@@ -144,56 +270,126 @@ func (vdc *VDC) Speed() uint64 {
 //
 // For example, if the speed is 3.1 GHz and the used amount is 49.6, the core count is 16.
 func (vdc *VDC) CoreCount() uint64 {
-	speed := vdc.Speed()
-	if speed == 0 {
-		return 0
+	count, err := vdc.CoreCountContext(context.Background())
+	vdc.swallowed("CoreCount", err)
+	return count
+}
+
+// CoreCountContext is the same as CoreCount, but aborts if ctx is cancelled.
+func (vdc *VDC) CoreCountContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdc.startSpan(ctx, "CoreCount")
+	defer func() { endSpan(span, err) }()
+	avdc, err := vdc.adminVDC(ctx)
+	if err != nil {
+		return 0, err
 	}
-	cores := uint64(0)
-	avdc, err := vdc.AdminOrg.GetAdminVDCById(vdc.Obj.Vdc.ID, false)
+	return coresOf(avdc)
+}
+
+// coresOf calculates the number of cores used by an admin VDC.
+func coresOf(avdc *govcd.AdminVdc) (uint64, error) {
+	speed, err := speedOf(avdc)
 	if err != nil {
-		return 0
+		return 0, err
 	}
+	cores := uint64(0)
 	for _, capacity := range avdc.AdminVdc.ComputeCapacity {
 		c := uint64(capacity.CPU.Used)
 		cores += c
 	}
-	return cores / speed
+	return cores / speed, nil
 }
 
 // Memory retrieves the amount of used memory to an oVDC, represented as a DataStorage type.
 func (vdc *VDC) Memory() DataStorage {
-	avdc, err := vdc.AdminOrg.GetAdminVDCById(vdc.Obj.Vdc.ID, false)
+	mem, err := vdc.MemoryContext(context.Background())
+	vdc.swallowed("Memory", err)
+	return mem
+}
+
+// MemoryContext is the same as Memory, but aborts if ctx is cancelled.
+func (vdc *VDC) MemoryContext(ctx context.Context) (_ DataStorage, err error) {
+	ctx, span := vdc.startSpan(ctx, "Memory")
+	defer func() { endSpan(span, err) }()
+	avdc, err := vdc.adminVDC(ctx)
 	if err != nil {
-		return 0
+		return 0, err
 	}
+	return memoryOf(avdc), nil
+}
+
+// memoryOf calculates the amount of memory used by an admin VDC.
+func memoryOf(avdc *govcd.AdminVdc) DataStorage {
 	bm := float64(0)
 	for _, capacity := range avdc.AdminVdc.ComputeCapacity {
-		switch capacity.Memory.Units {
-		case "KB":
-			bm += float64(capacity.Memory.Used * kb)
-		case "MB":
-			bm += float64(capacity.Memory.Used * mb)
-		case "GB":
-			bm += float64(capacity.Memory.Used * gb)
-		case "TB":
-			bm += float64(capacity.Memory.Used * tb)
-		default:
-			bm += float64(capacity.Memory.Used)
-		}
+		bm += bytesOf(capacity.Memory.Used, capacity.Memory.Units)
 	}
 	return DataStorage(bm)
 }
 
+// bytesOf converts an amount expressed in units to bytes.
+func bytesOf(amount int64, units string) float64 {
+	switch units {
+	case "KB":
+		return float64(amount * kb)
+	case "MB":
+		return float64(amount * mb)
+	case "GB":
+		return float64(amount * gb)
+	case "TB":
+		return float64(amount * tb)
+	default:
+		return float64(amount)
+	}
+}
+
+// adminVDC retrieves the admin representation of the VDC, from the client's cache if enabled.
+func (vdc *VDC) adminVDC(ctx context.Context) (*govcd.AdminVdc, error) {
+	avdc, err := cached(ctx, vdc.Client, vdc.Obj.Vdc.ID, func(ctx context.Context) (*govcd.AdminVdc, error) {
+		return call(ctx, vdc.Client, "GetAdminVDCById", func() (*govcd.AdminVdc, error) {
+			return vdc.AdminOrg.GetAdminVDCById(vdc.Obj.Vdc.ID, false)
+		}, vdc.logAttrs()...)
+	})
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve admin VDC '%s'", vdc.Obj.Vdc.ID)
+		return nil, err
+	}
+	return avdc, nil
+}
+
+// storageProfile retrieves a storage profile by its ID, from the client's cache if enabled.
+func (vdc *VDC) storageProfile(ctx context.Context, id string) (*types.VdcStorageProfile, error) {
+	attrs := append(vdc.logAttrs(), slog.String("storage_profile_id", id))
+	sp, err := cached(ctx, vdc.Client, id, func(ctx context.Context) (*types.VdcStorageProfile, error) {
+		return call(ctx, vdc.Client, "GetStorageProfileById", func() (*types.VdcStorageProfile, error) {
+			return vdc.Client.VCD.GetStorageProfileById(id)
+		}, attrs...)
+	})
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve storage profile '%s'", id)
+		return nil, err
+	}
+	return sp, nil
+}
+
 // allStorageProfiles retrieves all storage profiles for a VDC and filters out partial duplicates, for
 // example, when Veeam CDP creates a storage profile for the datastore.
-func (vdc *VDC) allStorageProfiles() ([]*types.VdcStorageProfile, error) {
-	avdc, err := vdc.AdminOrg.GetAdminVDCById(vdc.Obj.Vdc.ID, false)
+func (vdc *VDC) allStorageProfiles(ctx context.Context) ([]*types.VdcStorageProfile, error) {
+	avdc, err := vdc.adminVDC(ctx)
 	if err != nil {
 		return nil, err
 	}
+	return vdc.storageProfiles(ctx, avdc)
+}
+
+// storageProfiles retrieves the storage profiles referenced by an admin VDC.
+func (vdc *VDC) storageProfiles(ctx context.Context, avdc *govcd.AdminVdc) ([]*types.VdcStorageProfile, error) {
 	profiles := make([]*types.VdcStorageProfile, 0)
+	if avdc.AdminVdc.VdcStorageProfiles == nil {
+		return profiles, nil
+	}
 	for _, stor := range avdc.AdminVdc.VdcStorageProfiles.VdcStorageProfile {
-		sp, err := vdc.Client.VCD.GetStorageProfileById(stor.ID)
+		sp, err := vdc.storageProfile(ctx, stor.ID)
 		if err != nil {
 			return nil, err
 		}
@@ -202,60 +398,126 @@ func (vdc *VDC) allStorageProfiles() ([]*types.VdcStorageProfile, error) {
 	return profiles, nil
 }
 
-// storageProfile retrieves the default storage profile for the VDC.
-func (vdc *VDC) defaultStorageProfile() (*types.VdcStorageProfile, error) {
-	avdc, err := vdc.AdminOrg.GetAdminVDCById(vdc.Obj.Vdc.ID, false)
+// defaultStorageProfile retrieves the default storage profile for the VDC from its storage
+// profiles, which are cached by the client.
+func (vdc *VDC) defaultStorageProfile(ctx context.Context) (*types.VdcStorageProfile, error) {
+	profiles, err := vdc.allStorageProfiles(ctx)
 	if err != nil {
 		return nil, err
 	}
-	ref, err := avdc.GetDefaultStorageProfileReference()
+	def, err := defaultOf(profiles)
 	if err != nil {
+		err = errorx.Decorate(err, "failed to find default storage profile for VDC '%s'", vdc.Obj.Vdc.ID)
 		return nil, err
 	}
-	sp, err := vdc.Client.VCD.GetStorageProfileById(ref.ID)
-	if err != nil {
-		return nil, err
+	return def, nil
+}
+
+// defaultOf returns the default storage profile from a VDC's storage profiles.
+func defaultOf(profiles []*types.VdcStorageProfile) (*types.VdcStorageProfile, error) {
+	var def *types.VdcStorageProfile
+	for _, sp := range profiles {
+		if !sp.Default {
+			continue
+		}
+		if def != nil {
+			return nil, fmt.Errorf("more than one default storage profile: '%s' and '%s'", def.Name, sp.Name)
+		}
+		def = sp
 	}
-	return sp, nil
+	if def == nil {
+		return nil, errors.New("no default storage profile")
+	}
+	return def, nil
+}
+
+// storageOf calculates the amount of storage used by storage profiles.
+func storageOf(profiles ...*types.VdcStorageProfile) DataStorage {
+	bs := float64(0)
+	for _, prof := range profiles {
+		sb := prof.StorageUsedMB * mb
+		bs += float64(sb)
+	}
+	return DataStorage(bs)
 }
 
 // Storage retrieves the total amount of 'requested' storage for an oVDC using the oVDC default
 // storage policy.
 func (vdc *VDC) Storage() DataStorage {
-	profile, err := vdc.defaultStorageProfile()
+	stor, err := vdc.StorageContext(context.Background())
+	vdc.swallowed("Storage", err)
+	return stor
+}
+
+// StorageContext is the same as Storage, but aborts if ctx is cancelled.
+func (vdc *VDC) StorageContext(ctx context.Context) (_ DataStorage, err error) {
+	ctx, span := vdc.startSpan(ctx, "Storage")
+	defer func() { endSpan(span, err) }()
+	profile, err := vdc.defaultStorageProfile(ctx)
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	sb := profile.StorageUsedMB * mb
-	return DataStorage(sb)
+	return storageOf(profile), nil
 }
 
 // StorageAll retrieves the total amount of used storage for an oVDC, totaling the 'requested'
 // storage for all storage policies.
 func (vdc *VDC) StorageAll() DataStorage {
-	profiles, err := vdc.allStorageProfiles()
+	stor, err := vdc.StorageAllContext(context.Background())
+	vdc.swallowed("StorageAll", err)
+	return stor
+}
+
+// StorageAllContext is the same as StorageAll, but aborts if ctx is cancelled.
+func (vdc *VDC) StorageAllContext(ctx context.Context) (_ DataStorage, err error) {
+	ctx, span := vdc.startSpan(ctx, "StorageAll")
+	defer func() { endSpan(span, err) }()
+	profiles, err := vdc.allStorageProfiles(ctx)
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	bs := float64(0)
-	for _, prof := range profiles {
-		sb := prof.StorageUsedMB * mb
-		bs += float64(sb)
+	return storageOf(profiles...), nil
+}
+
+// vmList retrieves all VMs in the VDC matching filter.
+func (vdc *VDC) vmList(ctx context.Context, filter types.VmQueryFilter) ([]*types.QueryResultVMRecordType, error) {
+	ovdc, err := call(ctx, vdc.Client, "GetVDCById", func() (*govcd.Vdc, error) {
+		return vdc.AdminOrg.GetVDCById(vdc.Obj.Vdc.ID, false)
+	}, vdc.logAttrs()...)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve VDC '%s'", vdc.Obj.Vdc.ID)
+		return nil, err
 	}
-	return DataStorage(bs)
+	vms, err := call(ctx, vdc.Client, "QueryVmList", func() ([]*types.QueryResultVMRecordType, error) {
+		return ovdc.QueryVmList(filter)
+	}, vdc.logAttrs()...)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to query VMs for VDC '%s'", vdc.Obj.Vdc.ID)
+		return nil, err
+	}
+	return vms, nil
 }
 
 // VMCount retrieves the number of VMs deployed in the VDC.
 func (vdc *VDC) VMCount() uint64 {
-	ovdc, err := vdc.AdminOrg.GetVDCById(vdc.Obj.Vdc.ID, false)
-	if err != nil {
-		return 0
-	}
-	vms, err := ovdc.QueryVmList(types.VmQueryFilterAll)
+	count, err := vdc.VMCountContext(context.Background())
+	vdc.swallowed("VMCount", err)
+	return count
+}
+
+// VMCountContext is the same as VMCount, but aborts if ctx is cancelled.
+func (vdc *VDC) VMCountContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdc.startSpan(ctx, "VMCount")
+	defer func() { endSpan(span, err) }()
+	vms, err := vdc.vmList(ctx, types.VmQueryFilterAll)
 	if err != nil {
-		return 0
+		return 0, err
 	}
+	return countVMs(vms), nil
+}
 
+// countVMs counts the VMs that are deployed in a vApp and not deleted.
+func countVMs(vms []*types.QueryResultVMRecordType) uint64 {
 	count := uint64(0)
 	for _, vm := range vms {
 		vm := vm
@@ -268,14 +530,24 @@ func (vdc *VDC) VMCount() uint64 {
 
 // PoweredOnVMCount retrieves the number of powered-on VMs deployed in the VDC.
 func (vdc *VDC) PoweredOnVMCount() uint64 {
-	ovdc, err := vdc.AdminOrg.GetVDCById(vdc.Obj.Vdc.ID, false)
-	if err != nil {
-		return 0
-	}
-	vms, err := ovdc.QueryVmList(types.VmQueryFilterAll)
+	count, err := vdc.PoweredOnVMCountContext(context.Background())
+	vdc.swallowed("PoweredOnVMCount", err)
+	return count
+}
+
+// PoweredOnVMCountContext is the same as PoweredOnVMCount, but aborts if ctx is cancelled.
+func (vdc *VDC) PoweredOnVMCountContext(ctx context.Context) (_ uint64, err error) {
+	ctx, span := vdc.startSpan(ctx, "PoweredOnVMCount")
+	defer func() { endSpan(span, err) }()
+	vms, err := vdc.vmList(ctx, types.VmQueryFilterAll)
 	if err != nil {
-		return 0
+		return 0, err
 	}
+	return countPoweredOnVMs(vms), nil
+}
+
+// countPoweredOnVMs counts the powered-on VMs that are deployed in a vApp and not deleted.
+func countPoweredOnVMs(vms []*types.QueryResultVMRecordType) uint64 {
 	count := uint64(0)
 	for _, vm := range vms {
 		if vm.Status == types.VAppStatuses[4] && !vm.VAppTemplate && !vm.Deleted {
@@ -285,101 +557,139 @@ func (vdc *VDC) PoweredOnVMCount() uint64 {
 	return count
 }
 
-func (vdc *VDC) queryVMs(queries ...VMQuerySetter) ([]*types.QueryResultVMRecordType, error) {
-	ovdc, err := vdc.AdminOrg.GetVDCById(vdc.Obj.Vdc.ID, false)
+// queryVMs retrieves the VMs deployed in a vApp in the VDC that match all of the provided queries,
+// streaming them from VMs so that only matching VMs are held in memory.
+func (vdc *VDC) queryVMs(ctx context.Context, queries ...VMQuerySetter) ([]*types.QueryResultVMRecordType, error) {
+	results, err := vdc.vmResults(ctx, queries...)
 	if err != nil {
 		return nil, err
 	}
-	query := &VMQuery{
-		Name:      nil,
-		GuestOS:   nil,
-		PoweredOn: false,
-	}
-	for _, set := range queries {
-		set(query)
+	vms := make([]*types.QueryResultVMRecordType, 0, len(results))
+	for _, res := range results {
+		vms = append(vms, res.Record)
 	}
+	return vms, nil
+}
 
-	vms, err := ovdc.QueryVmList(types.VmQueryFilterOnlyDeployed)
-	if err != nil {
+// vmResults is the same as queryVMs, but returns each VM streamed by VMs with its query record.
+func (vdc *VDC) vmResults(ctx context.Context, queries ...VMQuerySetter) ([]VMResult, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make([]VMResult, 0)
+	for res := range vdc.VMs(ctx, queries...) {
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		results = append(results, res)
+	}
+	if err := ctx.Err(); err != nil {
 		return nil, err
 	}
-	if query.PoweredOn {
-		_vms := make([]*types.QueryResultVMRecordType, 0, len(vms))
-		for _, vm := range vms {
-			if vm.Status == types.VAppStatuses[4] {
-				_vms = append(_vms, vm)
-			}
-		}
-		vms = _vms
-	}
-	if query.Name != nil {
-		_vms := make([]*types.QueryResultVMRecordType, 0, len(vms))
-		for _, vm := range vms {
-			if query.Name.MatchString(vm.Name) {
-				_vms = append(_vms, vm)
-			}
-		}
-		vms = _vms
-	}
-	if query.GuestOS != nil {
-		_vms := make([]*types.QueryResultVMRecordType, 0, len(vms))
-		for _, vm := range vms {
-			if query.GuestOS.MatchString(vm.GuestOS) {
-				_vms = append(_vms, vm)
-			}
-		}
-		vms = _vms
+	return results, nil
+}
+
+// filterVMs returns the VMs matching all of the provided queries.
+func filterVMs(vms []*types.QueryResultVMRecordType, queries ...VMQuerySetter) []*types.QueryResultVMRecordType {
+	return newVMQuery(queries...).filter(vms)
+}
+
+// coresOfVMs totals the number of CPUs of VMs.
+func coresOfVMs(vms []*types.QueryResultVMRecordType) uint64 {
+	count := uint64(0)
+	for _, vm := range vms {
+		count += uint64(vm.Cpus)
 	}
-	return vms, nil
+	return count
 }
 
 // VMCountWithQuery retrieves the number of VMs matching all of the provided queries.
 // If PoweredOn is false (default), VMs that are both powered on or off will be included.
 func (vdc *VDC) VMCountWithQuery(queries ...VMQuerySetter) uint64 {
-	vms, err := vdc.queryVMs(queries...)
+	count, err := vdc.VMCountWithQueryContext(context.Background(), queries...)
+	vdc.swallowed("VMCountWithQuery", err)
+	return count
+}
+
+// VMCountWithQueryContext is the same as VMCountWithQuery, but aborts if ctx is cancelled.
+func (vdc *VDC) VMCountWithQueryContext(ctx context.Context, queries ...VMQuerySetter) (_ uint64, err error) {
+	ctx, span := vdc.startSpan(ctx, "VMCountWithQuery")
+	defer func() { endSpan(span, err) }()
+	vms, err := vdc.queryVMs(ctx, queries...)
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	return uint64(len(vms))
+	return uint64(len(vms)), nil
 }
 
 // VMCoreCountWithQuery retrieves the number cores on VMs matching all of the provided queries.
 // If PoweredOn is false (default), VMs that are both powered on or off will be included.
 func (vdc *VDC) VMCoreCountWithQuery(queries ...VMQuerySetter) uint64 {
-	vms, err := vdc.queryVMs(queries...)
+	count, err := vdc.VMCoreCountWithQueryContext(context.Background(), queries...)
+	vdc.swallowed("VMCoreCountWithQuery", err)
+	return count
+}
+
+// VMCoreCountWithQueryContext is the same as VMCoreCountWithQuery, but aborts if ctx is cancelled.
+func (vdc *VDC) VMCoreCountWithQueryContext(ctx context.Context, queries ...VMQuerySetter) (_ uint64, err error) {
+	ctx, span := vdc.startSpan(ctx, "VMCoreCountWithQuery")
+	defer func() { endSpan(span, err) }()
+	vms, err := vdc.queryVMs(ctx, queries...)
 	if err != nil {
-		return 0
+		return 0, err
 	}
-	count := uint64(0)
-	for _, vm := range vms {
-		count += uint64(vm.Cpus)
-	}
-	return count
+	return coresOfVMs(vms), nil
 }
 
-// Org retrieves a vCloud Organization object.
+// Org retrieves a vCloud Organization object. If the org does not exist, the error matches
+// ErrOrgNotFound. Errors from vCloud match ErrUnauthorized, ErrForbidden, or ErrTransient where
+// applicable.
 func (client *Client) Org(orgID string) (*govcd.AdminOrg, error) {
+	return client.OrgContext(context.Background(), orgID)
+}
+
+// OrgContext is the same as Org, but aborts if ctx is cancelled.
+func (client *Client) OrgContext(ctx context.Context, orgID string) (_ *govcd.AdminOrg, err error) {
+	ctx, span := client.startSpan(ctx, "Client.Org")
+	defer func() { endSpan(span, err) }()
 	if !strings.HasPrefix(orgID, "urn:vcloud:org:") {
 		orgID = fmt.Sprintf("urn:vcloud:org:%s", orgID)
 	}
-	org, err := client.VCD.GetAdminOrgById(orgID)
+	org, err := call(ctx, client, "GetAdminOrgById", func() (*govcd.AdminOrg, error) {
+		return client.VCD.GetAdminOrgById(orgID)
+	}, slog.String("org_id", orgID))
 	if err != nil {
-		err = errorx.Decorate(err, "failed to retrieve org '%s'", orgID)
+		err = errorx.Decorate(notFound(err, ErrOrgNotFound), "failed to retrieve org '%s'", orgID)
 		return nil, err
 	}
 	return org, nil
 }
 
 // VDC retrieves a single VDC associated with an organization by its ID and provides a wrapper for
-// utilization functions for each VDC.
+// utilization functions for each VDC. If the org or VDC does not exist, the error matches
+// ErrOrgNotFound or ErrVDCNotFound. Errors from vCloud match ErrUnauthorized, ErrForbidden, or
+// ErrTransient where applicable.
 func (client *Client) VDC(orgID string, id string) (*VDC, error) {
-	org, err := client.Org(orgID)
+	return client.VDCContext(context.Background(), orgID, id)
+}
+
+// VDCContext is the same as VDC, but aborts if ctx is cancelled.
+func (client *Client) VDCContext(ctx context.Context, orgID string, id string) (_ *VDC, err error) {
+	ctx, span := client.startSpan(ctx, "Client.VDC")
+	defer func() { endSpan(span, err) }()
+	org, err := client.OrgContext(ctx, orgID)
 	if err != nil {
 		return nil, err
 	}
-	obj, err := org.GetVDCById(id, false)
+	return client.orgVDC(ctx, org, id)
+}
+
+// orgVDC retrieves a single VDC associated with an organization by its ID.
+func (client *Client) orgVDC(ctx context.Context, org *govcd.AdminOrg, id string) (*VDC, error) {
+	obj, err := call(ctx, client, "GetVDCById", func() (*govcd.Vdc, error) {
+		return org.GetVDCById(id, false)
+	}, slog.String("org_id", org.AdminOrg.ID), slog.String("vdc_id", id))
 	if err != nil {
-		err = errorx.Decorate(err, "failed to retrieve VDC '%s' for org '%s'", id, orgID)
+		err = errorx.Decorate(notFound(err, ErrVDCNotFound), "failed to retrieve VDC '%s' for org '%s'", id, org.AdminOrg.ID)
 		return nil, err
 	}
 	vdc := &VDC{
@@ -391,15 +701,30 @@ func (client *Client) VDC(orgID string, id string) (*VDC, error) {
 }
 
 // VDCs retrieves all VDCs associated with an organization and provides a wrapper for utilization
-// functions for each VDC.
+// functions for each VDC. If the org does not exist, the error matches ErrOrgNotFound. Errors from
+// vCloud match ErrUnauthorized, ErrForbidden, or ErrTransient where applicable.
 func (client *Client) VDCs(orgID string) (VDCs, error) {
-	org, err := client.Org(orgID)
+	return client.VDCsContext(context.Background(), orgID)
+}
+
+// VDCsContext is the same as VDCs, but aborts if ctx is cancelled.
+func (client *Client) VDCsContext(ctx context.Context, orgID string) (_ VDCs, err error) {
+	ctx, span := client.startSpan(ctx, "Client.VDCs")
+	defer func() { endSpan(span, err) }()
+	org, err := client.OrgContext(ctx, orgID)
 	if err != nil {
 		return nil, err
 	}
-	vdcs, err := org.GetAllVDCs(false)
+	return client.orgVDCs(ctx, org)
+}
+
+// orgVDCs retrieves all VDCs associated with an organization.
+func (client *Client) orgVDCs(ctx context.Context, org *govcd.AdminOrg) (VDCs, error) {
+	vdcs, err := call(ctx, client, "GetAllVDCs", func() ([]*govcd.Vdc, error) {
+		return org.GetAllVDCs(false)
+	}, slog.String("org_id", org.AdminOrg.ID))
 	if err != nil {
-		err = errorx.Decorate(err, "failed to retrieve VDCs for org '%s'", orgID)
+		err = errorx.Decorate(err, "failed to retrieve VDCs for org '%s'", org.AdminOrg.ID)
 		return nil, err
 	}
 	vdcObjs := make([]VDC, 0, len(vdcs))
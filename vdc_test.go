@@ -1,11 +1,15 @@
 package vcdusage_test
 
 import (
+	"context"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
 	"go.stellar.af/go-vcdusage"
 )
 
@@ -89,4 +93,100 @@ func Test_VDCs(t *testing.T) {
 		assert.NotZero(t, count, "no VMs matching query")
 		assert.Equal(t, Env.WindowsCountOn, count, "mismatching Windows VM count: %v != %v", Env.WindowsCountOn, count)
 	})
+	t.Run("cancelled context", func(t *testing.T) {
+		t.Parallel()
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := client.VDCsContext(ctx, Env.OrgID)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = vdcs.CoreCountContext(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+		_, err = vdcs.VMCountContext(ctx)
+		assert.ErrorIs(t, err, context.Canceled)
+	})
+	t.Run("VDC errors", func(t *testing.T) {
+		t.Parallel()
+		require.NotEmpty(t, vdcs)
+		bogus := vcdusage.VDC{
+			Obj:      &govcd.Vdc{Vdc: &types.Vdc{ID: "urn:vcloud:vdc:00000000-0000-0000-0000-000000000000", Name: "bogus"}},
+			AdminOrg: vdcs[0].AdminOrg,
+			Client:   client,
+		}
+		withBogus := append(vcdusage.VDCs{bogus}, vdcs...)
+		cores, err := withBogus.CoreCountContext(context.Background())
+		assert.Zero(t, cores, "partial core count returned")
+		var vdcErr *vcdusage.VDCError
+		require.ErrorAs(t, err, &vdcErr)
+		assert.Equal(t, "bogus", vdcErr.Name)
+	})
+}
+
+func Test_VDCCache(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+		vcdusage.CacheTTL(time.Minute),
+	)
+	require.NoError(t, err)
+	vdc, err := client.VDC(Env.OrgID, Env.VdcID)
+	require.NoError(t, err)
+	assert.Equal(t, Env.Cores, vdc.CoreCount())
+	assert.Equal(t, Env.Memory, vdc.Memory().GB())
+	stats := client.CacheStats()
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Hits)
+	client.InvalidateCache(vdc.Obj.Vdc.ID)
+	assert.Equal(t, Env.Cores, vdc.CoreCount())
+	assert.Equal(t, uint64(2), client.CacheStats().Misses)
+}
+
+func Test_VDCVMs(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	vdc, err := client.VDC(Env.OrgID, Env.VdcID)
+	require.NoError(t, err)
+	ctx := context.Background()
+	count := uint64(0)
+	for res := range vdc.VMs(ctx, vcdusage.VMPoweredOn()) {
+		require.NoError(t, res.Err)
+		assert.Equal(t, types.VAppStatuses[4], res.VM.Status)
+		count++
+	}
+	require.NoError(t, ctx.Err())
+	assert.Equal(t, vdc.VMCountWithQuery(vcdusage.VMPoweredOn()), count)
+}
+
+func Test_VDCListVMs(t *testing.T) {
+	u, err := vcdusage.ParseURL(Env.URL)
+	require.NoError(t, err)
+	client, err := vcdusage.New(
+		vcdusage.Insecure(),
+		vcdusage.URL(u),
+		vcdusage.Username(Env.Username),
+		vcdusage.Password(Env.Password),
+	)
+	require.NoError(t, err)
+	vdc, err := client.VDC(Env.OrgID, Env.VdcID)
+	require.NoError(t, err)
+	vms, err := vdc.ListVMsContext(context.Background(), vcdusage.VMPoweredOn())
+	require.NoError(t, err)
+	assert.Equal(t, vdc.VMCountWithQuery(vcdusage.VMPoweredOn()), uint64(len(vms)))
+	for _, vm := range vms {
+		assert.Equal(t, vdc.Obj.Vdc.ID, vm.VDCID)
+		assert.Equal(t, types.VAppStatuses[4], vm.Status)
+		assert.NotZero(t, vm.CPUs)
+		assert.NotZero(t, vm.CoresPerSocket)
+		assert.NotZero(t, vm.Memory)
+	}
 }
@@ -0,0 +1,145 @@
+package vcdusage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+// MinAPIVersion is the lowest vCloud API version supported by go-vcloud-director, which is used
+// for every request to vCloud. It is supported by vCloud 10.4 and later.
+const MinAPIVersion = "37.0"
+
+// MaxAPIVersion is the highest vCloud API version negotiated with vCloud.
+const MaxAPIVersion = "38.1"
+
+// ErrCfgAPIVersion indicates a pinned API version is not in the form 'major.minor'.
+var ErrCfgAPIVersion = errors.New("API version must be in the form 'major.minor'")
+
+// ErrAPIVersionUnsupported indicates vCloud does not support the pinned API version, or any API
+// version from MinAPIVersion to MaxAPIVersion.
+var ErrAPIVersionUnsupported = errors.New("API version not supported by vCloud")
+
+// apiVersionPattern matches a vCloud API version.
+var apiVersionPattern = regexp.MustCompile(`^\d+\.\d+$`)
+
+// APIVersionError indicates a feature requires a newer vCloud API version than vCloud supports.
+// It is returned by New, wrapped with ErrAPIVersionUnsupported, if vCloud only supports API
+// versions older than MinAPIVersion.
+type APIVersionError struct {
+	// Feature is the feature that requires a newer API version.
+	Feature string
+	// Required is the lowest API version supporting the feature.
+	Required string
+	// Version is the newest API version supported by vCloud, or the pinned API version.
+	Version string
+}
+
+// Error implements the error interface.
+func (e *APIVersionError) Error() string {
+	return fmt.Sprintf("%s requires vCloud API version %s, but version %s is used", e.Feature, e.Required, e.Version)
+}
+
+// APIVersion pins the vCloud API version used by the client instead of negotiating the highest
+// version supported by both the client and vCloud. vCloud must support the version, and it must not
+// be older than MinAPIVersion.
+func APIVersion(version string) Option {
+	return func(opts *Options) {
+		opts.APIVersion = version
+	}
+}
+
+// parseAPIVersion splits a vCloud API version into its major and minor versions.
+func parseAPIVersion(version string) (major, minor int, ok bool) {
+	majorStr, minorStr, found := strings.Cut(version, ".")
+	if !found {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(minorStr)
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
+
+// compareAPIVersions returns -1 if a is older than b, 1 if a is newer than b, and 0 if they are
+// the same version. Versions that cannot be parsed are older than all others.
+func compareAPIVersions(a, b string) int {
+	aMajor, aMinor, aOK := parseAPIVersion(a)
+	bMajor, bMinor, bOK := parseAPIVersion(b)
+	switch {
+	case !aOK || !bOK:
+		if aOK == bOK {
+			return 0
+		}
+		if aOK {
+			return 1
+		}
+		return -1
+	case aMajor != bMajor:
+		return compareInts(aMajor, bMajor)
+	default:
+		return compareInts(aMinor, bMinor)
+	}
+}
+
+// compareInts returns -1 if a < b, 1 if a > b, and 0 if a == b.
+func compareInts(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// negotiateAPIVersion selects the API version used by vcd from the versions supported by vCloud:
+// the pinned version if set, otherwise the highest supported version no newer than MaxAPIVersion.
+// Versions older than MinAPIVersion are rejected with an *APIVersionError.
+func (opts *Options) negotiateAPIVersion(vcd *govcd.VCDClient) error {
+	endpoint := vcd.Client.VCDHREF
+	endpoint.Path += "/versions"
+	supported := &govcd.SupportedVersions{}
+	_, err := vcd.Client.ExecuteRequest(endpoint.String(), http.MethodGet, "", "error fetching API versions: %s", nil, supported)
+	if err != nil {
+		return err
+	}
+	negotiated := ""
+	for _, info := range supported.VersionInfos {
+		if opts.APIVersion != "" {
+			if info.Version == opts.APIVersion {
+				negotiated = info.Version
+			}
+			continue
+		}
+		if compareAPIVersions(info.Version, MaxAPIVersion) <= 0 && compareAPIVersions(info.Version, negotiated) > 0 {
+			negotiated = info.Version
+		}
+	}
+	switch {
+	case opts.APIVersion != "" && negotiated == "":
+		return fmt.Errorf("%w: pinned version %s", ErrAPIVersionUnsupported, opts.APIVersion)
+	case negotiated == "":
+		return fmt.Errorf("%w: no version up to %s", ErrAPIVersionUnsupported, MaxAPIVersion)
+	case compareAPIVersions(negotiated, MinAPIVersion) < 0:
+		versionErr := &APIVersionError{Feature: "go-vcloud-director", Required: MinAPIVersion, Version: negotiated}
+		return fmt.Errorf("%w: %w", ErrAPIVersionUnsupported, versionErr)
+	}
+	vcd.Client.APIVersion = negotiated
+	return nil
+}
+
+// APIVersion returns the vCloud API version used by the client.
+func (client *Client) APIVersion() string {
+	return client.VCD.Client.APIVersion
+}
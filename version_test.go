@@ -0,0 +1,88 @@
+package vcdusage
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+)
+
+func testVersionsServer(t *testing.T, versions ...string) *govcd.VCDClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/versions" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var body strings.Builder
+		body.WriteString(`<?xml version="1.0" encoding="UTF-8"?><SupportedVersions xmlns="http://www.vmware.com/vcloud/versions">`)
+		for _, version := range versions {
+			fmt.Fprintf(&body, "<VersionInfo><Version>%s</Version></VersionInfo>", version)
+		}
+		body.WriteString("</SupportedVersions>")
+		w.Header().Set("Content-Type", "application/xml")
+		_, _ = w.Write([]byte(body.String()))
+	}))
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL + "/api")
+	require.NoError(t, err)
+	return govcd.NewVCDClient(*u, false)
+}
+
+func Test_compareAPIVersions(t *testing.T) {
+	assert.Equal(t, 0, compareAPIVersions("37.0", "37.0"))
+	assert.Equal(t, -1, compareAPIVersions("37.0", "37.1"))
+	assert.Equal(t, 1, compareAPIVersions("38.0", "37.2"))
+	assert.Equal(t, -1, compareAPIVersions("9.0", "10.0"))
+	assert.Equal(t, 1, compareAPIVersions("37.0", ""))
+}
+
+func Test_negotiateAPIVersion(t *testing.T) {
+	t.Run("highest", func(t *testing.T) {
+		t.Parallel()
+		vcd := testVersionsServer(t, "36.0", "37.0", "37.2", "38.1", "39.0")
+		require.NoError(t, (&Options{}).negotiateAPIVersion(vcd))
+		assert.Equal(t, "38.1", vcd.Client.APIVersion)
+	})
+	t.Run("pinned", func(t *testing.T) {
+		t.Parallel()
+		vcd := testVersionsServer(t, "36.0", "37.0", "38.1")
+		require.NoError(t, (&Options{APIVersion: "37.0"}).negotiateAPIVersion(vcd))
+		assert.Equal(t, "37.0", vcd.Client.APIVersion)
+	})
+	t.Run("pinned older than minimum", func(t *testing.T) {
+		t.Parallel()
+		vcd := testVersionsServer(t, "36.0", "37.0", "38.1")
+		err := (&Options{APIVersion: "36.0"}).negotiateAPIVersion(vcd)
+		assert.ErrorIs(t, err, ErrAPIVersionUnsupported)
+		var versionErr *APIVersionError
+		require.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, "36.0", versionErr.Version)
+	})
+	t.Run("pinned unsupported", func(t *testing.T) {
+		t.Parallel()
+		vcd := testVersionsServer(t, "37.0", "38.1")
+		assert.ErrorIs(t, (&Options{APIVersion: "36.0"}).negotiateAPIVersion(vcd), ErrAPIVersionUnsupported)
+	})
+	t.Run("older than minimum", func(t *testing.T) {
+		t.Parallel()
+		vcd := testVersionsServer(t, "35.0", "36.0")
+		err := (&Options{}).negotiateAPIVersion(vcd)
+		assert.ErrorIs(t, err, ErrAPIVersionUnsupported)
+		var versionErr *APIVersionError
+		require.ErrorAs(t, err, &versionErr)
+		assert.Equal(t, MinAPIVersion, versionErr.Required)
+		assert.Equal(t, "36.0", versionErr.Version)
+	})
+	t.Run("newer than maximum", func(t *testing.T) {
+		t.Parallel()
+		vcd := testVersionsServer(t, "39.0", "40.0")
+		assert.ErrorIs(t, (&Options{}).negotiateAPIVersion(vcd), ErrAPIVersionUnsupported)
+	})
+}
@@ -0,0 +1,219 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/destel/rill"
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// VM is a virtual machine deployed in a vApp, with the details that make up its usage.
+type VM struct {
+	ID                 string
+	Name               string
+	VAppID             string
+	VAppName           string
+	VDCID              string
+	VDCName            string
+	OrgID              string
+	OrgName            string
+	Status             string
+	GuestOS            string
+	CPUs               uint64
+	CoresPerSocket     uint64
+	Memory             DataStorage
+	ProvisionedStorage DataStorage
+	UsedStorage        DataStorage
+	HardwareVersion    int
+	StorageProfile     string
+	CreatedAt          time.Time
+}
+
+// vmMetrics is the current usage of a VM as reported by vCloud.
+type vmMetrics struct {
+	Metrics []struct {
+		Name  string  `xml:"name,attr"`
+		Unit  string  `xml:"unit,attr"`
+		Value float64 `xml:"value,attr"`
+	} `xml:"Metric"`
+}
+
+// vmUsedStorageMetric is the name of the metric reporting the storage used by a VM in KB.
+const vmUsedStorageMetric = "disk.used.latest"
+
+// vmOf converts a VM query record of the VDC to a VM. Cores per socket and used storage are not
+// reported by the Query API and are left zero for vmDetails.
+func (vdc *VDC) vmOf(rec *types.QueryResultVMRecordType) *VM {
+	vm := &VM{
+		ID:              urnOf("vm", rec.HREF),
+		Name:            rec.Name,
+		VAppName:        rec.ContainerName,
+		VDCID:           vdc.Obj.Vdc.ID,
+		VDCName:         vdc.Obj.Vdc.Name,
+		Status:          rec.Status,
+		GuestOS:         rec.GuestOS,
+		CPUs:            uint64(rec.Cpus),
+		Memory:          DataStorage(rec.MemoryMB * mb),
+		HardwareVersion: rec.HardwareVersion,
+		StorageProfile:  rec.StorageProfileName,
+	}
+	if rec.ContainerID != "" {
+		vm.VAppID = urnOf("vapp", rec.ContainerID)
+	}
+	if rec.VdcName != "" {
+		vm.VDCName = rec.VdcName
+	}
+	if vdc.AdminOrg != nil && vdc.AdminOrg.AdminOrg != nil {
+		vm.OrgID = vdc.AdminOrg.AdminOrg.ID
+		vm.OrgName = vdc.AdminOrg.AdminOrg.Name
+	}
+	if allocated, err := strconv.ParseInt(rec.TotalStorageAllocatedMb, 10, 64); err == nil {
+		vm.ProvisionedStorage = DataStorage(allocated * mb)
+	}
+	if created, err := time.Parse(time.RFC3339, rec.DateCreated); err == nil {
+		vm.CreatedAt = created
+	}
+	return vm
+}
+
+// vmDetails retrieves the cores per socket and used storage of vm, which is referenced by href.
+func (vdc *VDC) vmDetails(ctx context.Context, href string, vm *VM) error {
+	attrs := append(vdc.logAttrs(), slog.String("vm_id", vm.ID), slog.String("vm_name", vm.Name))
+	spec, err := call(ctx, vdc.Client, "GetVMByHref", func() (*types.Vm, error) {
+		spec := &types.Vm{}
+		_, err := vdc.Client.VCD.Client.ExecuteRequest(href, http.MethodGet, "", "error retrieving vm: %s", nil, spec)
+		if err != nil {
+			return nil, err
+		}
+		return spec, nil
+	}, attrs...)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve VM '%s'", vm.ID)
+		return err
+	}
+	if spec.VmSpecSection != nil && spec.VmSpecSection.NumCoresPerSocket != nil {
+		vm.CoresPerSocket = uint64(*spec.VmSpecSection.NumCoresPerSocket)
+	}
+	metrics, err := call(ctx, vdc.Client, "GetVMMetrics", func() (*vmMetrics, error) {
+		metrics := &vmMetrics{}
+		_, err := vdc.Client.VCD.Client.ExecuteRequest(href+"/metrics/current", http.MethodGet, "", "error retrieving vm metrics: %s", nil, metrics)
+		if err != nil {
+			return nil, err
+		}
+		return metrics, nil
+	}, attrs...)
+	if err != nil {
+		err = errorx.Decorate(err, "failed to retrieve metrics of VM '%s'", vm.ID)
+		return err
+	}
+	for _, metric := range metrics.Metrics {
+		if metric.Name == vmUsedStorageMetric {
+			vm.UsedStorage = DataStorage(metric.Value * kb)
+		}
+	}
+	return nil
+}
+
+// ListVMs retrieves the VMs deployed in a vApp in the VDC that match all of the provided queries.
+// If PoweredOn is false (default), VMs that are both powered on or off will be included.
+func (vdc *VDC) ListVMs(queries ...VMQuerySetter) []VM {
+	vms, err := vdc.ListVMsContext(context.Background(), queries...)
+	vdc.swallowed("ListVMs", err)
+	return vms
+}
+
+// ListVMsContext is the same as ListVMs, but aborts if ctx is cancelled. The Query API does not
+// report the cores per socket or used storage of a VM, so if VMWithDetails is set, each matching
+// VM is also retrieved individually, as many at once as the client's concurrency allows. VMs that
+// are deleted before they are retrieved are left out.
+func (vdc *VDC) ListVMsContext(ctx context.Context, queries ...VMQuerySetter) ([]VM, error) {
+	return vdc.listVMs(ctx, vdc.Client.concurrency(), queries...)
+}
+
+// listVMs is the same as ListVMsContext, but retrieves the details of at most concurrency VMs at
+// once.
+func (vdc *VDC) listVMs(ctx context.Context, concurrency int, queries ...VMQuerySetter) (_ []VM, err error) {
+	ctx, span := vdc.startSpan(ctx, "ListVMs")
+	defer func() { endSpan(span, err) }()
+	found, err := vdc.vmResults(ctx, queries...)
+	if err != nil {
+		return nil, err
+	}
+	details := newVMQuery(queries...).Details
+	results := rill.OrderedMap(rill.FromSlice(found, nil), concurrency, func(res VMResult) (*VM, error) {
+		if !details {
+			return res.VM, nil
+		}
+		if err := vdc.vmDetails(ctx, res.Record.HREF, res.VM); err != nil {
+			if isNotFound(err) {
+				return nil, nil
+			}
+			return nil, err
+		}
+		return res.VM, nil
+	})
+	vms := make([]VM, 0, len(found))
+	errs := make([]error, 0)
+	for res := range results {
+		if res.Error != nil {
+			errs = append(errs, res.Error)
+			continue
+		}
+		if res.Value != nil {
+			vms = append(vms, *res.Value)
+		}
+	}
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return vms, nil
+}
+
+// ListVMs retrieves the VMs deployed in a vApp in all VDCs that match all of the provided queries.
+// If PoweredOn is false (default), VMs that are both powered on or off will be included.
+func (vdcs VDCs) ListVMs(queries ...VMQuerySetter) []VM {
+	lists, errs := each(context.Background(), vdcs, vdcs.listVMs(queries...))
+	vdcs.swallowed("ListVMs", errs)
+	return flattenVMs(lists)
+}
+
+// ListVMsContext is the same as ListVMs, but aborts if ctx is cancelled. If the VMs cannot be
+// retrieved for any VDC, an error identifying each failed VDC is returned. VMs of several VDCs may
+// be retrieved at once, but no more in total than the client's concurrency allows.
+func (vdcs VDCs) ListVMsContext(ctx context.Context, queries ...VMQuerySetter) (_ []VM, err error) {
+	ctx, span := vdcs.startSpan(ctx, "ListVMs")
+	defer func() { endSpan(span, err) }()
+	lists, errs := each(ctx, vdcs, vdcs.listVMs(queries...))
+	if len(errs) != 0 {
+		return nil, errors.Join(errs...)
+	}
+	return flattenVMs(lists), nil
+}
+
+// listVMs returns a function listing the VMs of one of the VDCs. The client's concurrency is
+// divided between the VDCs listed at once and the VMs retrieved at once by each of them, so that
+// no more requests are made at once than the client's concurrency allows.
+func (vdcs VDCs) listVMs(queries ...VMQuerySetter) func(*VDC, context.Context) ([]VM, error) {
+	concurrency := 1
+	if len(vdcs) != 0 {
+		concurrency = max(1, vdcs[0].Client.concurrency()/vdcs.concurrency())
+	}
+	return func(vdc *VDC, ctx context.Context) ([]VM, error) {
+		return vdc.listVMs(ctx, concurrency, queries...)
+	}
+}
+
+// flattenVMs combines the VMs of each VDC into a single list, preserving their order.
+func flattenVMs(lists [][]VM) []VM {
+	vms := make([]VM, 0)
+	for _, list := range lists {
+		vms = append(vms, list...)
+	}
+	return vms
+}
@@ -2,13 +2,25 @@ package vcdusage
 
 import (
 	"fmt"
+	"net/url"
 	"regexp"
+	"strings"
+
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
 )
 
 type VMQuery struct {
 	Name      *regexp.Regexp
 	GuestOS   *regexp.Regexp
 	PoweredOn bool
+	// Details determines if ListVMs retrieves each VM individually for the details the Query API
+	// does not report.
+	Details bool
+	// nameContains and guestOSContains are the substrings matched by Name and GuestOS, if they
+	// were set by VMWithNameContaining and VMWithGuestOSContaining, so that the query can be
+	// evaluated by vCloud.
+	nameContains    string
+	guestOSContains string
 }
 
 type VMQuerySetter func(*VMQuery)
@@ -16,24 +28,28 @@ type VMQuerySetter func(*VMQuery)
 func VMWithNameContaining(contains string) VMQuerySetter {
 	return func(q *VMQuery) {
 		q.Name = regexp.MustCompile(fmt.Sprintf("(?i).*%s.*", contains))
+		q.nameContains = contains
 	}
 }
 
 func VMWithNameMatching(pattern *regexp.Regexp) VMQuerySetter {
 	return func(q *VMQuery) {
 		q.Name = pattern
+		q.nameContains = ""
 	}
 }
 
 func VMWithGuestOSContaining(contains string) VMQuerySetter {
 	return func(q *VMQuery) {
 		q.GuestOS = regexp.MustCompile(fmt.Sprintf("(?i).*%s.*", contains))
+		q.guestOSContains = contains
 	}
 }
 
 func VMWithGuestOSMatching(pattern *regexp.Regexp) VMQuerySetter {
 	return func(q *VMQuery) {
 		q.GuestOS = regexp.MustCompile("(?i)" + pattern.String())
+		q.guestOSContains = ""
 	}
 }
 
@@ -42,3 +58,91 @@ func VMPoweredOn() VMQuerySetter {
 		q.PoweredOn = true
 	}
 }
+
+// VMWithDetails retrieves the cores per socket and used storage of each VM listed by ListVMs,
+// which takes two more requests per VM. They are left zero otherwise.
+func VMWithDetails() VMQuerySetter {
+	return func(q *VMQuery) {
+		q.Details = true
+	}
+}
+
+// newVMQuery creates a VM query from setters.
+func newVMQuery(queries ...VMQuerySetter) *VMQuery {
+	query := &VMQuery{
+		Name:      nil,
+		GuestOS:   nil,
+		PoweredOn: false,
+	}
+	for _, set := range queries {
+		set(query)
+	}
+	return query
+}
+
+// fiqlReserved are the characters with a meaning in FIQL filters, which cannot be matched by a
+// filter value.
+const fiqlReserved = ";,()=!<>*\"'"
+
+// fiqlValue returns contains as a URL-encoded FIQL wildcard matching values containing it, or false
+// if contains is a regular expression or cannot be expressed in a FIQL filter.
+func fiqlValue(contains string) (string, bool) {
+	if contains == "" || regexp.QuoteMeta(contains) != contains || strings.ContainsAny(contains, fiqlReserved) {
+		return "", false
+	}
+	return "*" + strings.ReplaceAll(url.QueryEscape(contains), "+", "%20") + "*", true
+}
+
+// fiql compiles the predicates of the query that can be evaluated by vCloud into a URL-encoded FIQL
+// filter for the Query API, e.g. 'name==*web*;status==POWERED_ON', and returns the query of the
+// predicates that must still be evaluated by filter. vCloud matches wildcard values
+// case-insensitively, as VMWithNameContaining and VMWithGuestOSContaining do, so the name and
+// guest OS filters only narrow the results retrieved from vCloud, and their regular expressions
+// are always evaluated as well.
+func (q *VMQuery) fiql() (string, *VMQuery) {
+	conditions := make([]string, 0, 3)
+	rest := *q
+	if value, ok := fiqlValue(q.nameContains); ok && q.Name != nil {
+		conditions = append(conditions, "name=="+value)
+	}
+	if value, ok := fiqlValue(q.guestOSContains); ok && q.GuestOS != nil {
+		conditions = append(conditions, "guestOs=="+value)
+	}
+	if q.PoweredOn {
+		conditions = append(conditions, "status=="+types.VAppStatuses[4])
+		rest.PoweredOn = false
+	}
+	return strings.Join(conditions, ";"), &rest
+}
+
+// filter returns the VMs matching all of the query's predicates.
+func (q *VMQuery) filter(vms []*types.QueryResultVMRecordType) []*types.QueryResultVMRecordType {
+	if q.PoweredOn {
+		_vms := make([]*types.QueryResultVMRecordType, 0, len(vms))
+		for _, vm := range vms {
+			if vm.Status == types.VAppStatuses[4] {
+				_vms = append(_vms, vm)
+			}
+		}
+		vms = _vms
+	}
+	if q.Name != nil {
+		_vms := make([]*types.QueryResultVMRecordType, 0, len(vms))
+		for _, vm := range vms {
+			if q.Name.MatchString(vm.Name) {
+				_vms = append(_vms, vm)
+			}
+		}
+		vms = _vms
+	}
+	if q.GuestOS != nil {
+		_vms := make([]*types.QueryResultVMRecordType, 0, len(vms))
+		for _, vm := range vms {
+			if q.GuestOS.MatchString(vm.GuestOS) {
+				_vms = append(_vms, vm)
+			}
+		}
+		vms = _vms
+	}
+	return vms
+}
@@ -0,0 +1,39 @@
+package vcdusage
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_fiql(t *testing.T) {
+	type caseT struct {
+		name    string
+		queries []VMQuerySetter
+		filter  string
+	}
+	cases := []caseT{
+		{"none", nil, ""},
+		{"powered on", []VMQuerySetter{VMPoweredOn()}, "status==POWERED_ON"},
+		{"name", []VMQuerySetter{VMWithNameContaining("web")}, "name==*web*"},
+		{"space", []VMQuerySetter{VMWithGuestOSContaining("Server 2022")}, "guestOs==*Server%202022*"},
+		{"all", []VMQuerySetter{VMWithNameContaining("web"), VMWithGuestOSContaining("Windows"), VMPoweredOn()}, "name==*web*;guestOs==*Windows*;status==POWERED_ON"},
+		{"regex", []VMQuerySetter{VMWithNameContaining("[0-9]+")}, ""},
+		{"reserved", []VMQuerySetter{VMWithNameContaining("1;2"), VMPoweredOn()}, "status==POWERED_ON"},
+		{"matching", []VMQuerySetter{VMWithNameMatching(regexp.MustCompile("^1"))}, ""},
+		{"replaced", []VMQuerySetter{VMWithNameContaining("1"), VMWithNameMatching(regexp.MustCompile("^1"))}, ""},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			t.Parallel()
+			query := newVMQuery(c.queries...)
+			filter, rest := query.fiql()
+			assert.Equal(t, c.filter, filter)
+			assert.False(t, rest.PoweredOn)
+			assert.Equal(t, query.Name, rest.Name)
+			assert.Equal(t, query.GuestOS, rest.GuestOS)
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package vcdusage
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+func Test_ListVMs(t *testing.T) {
+	t.Run("vdc", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 1)
+		vdc := testQueryVDC(t, srv, testVDC1)
+		vdc.AdminOrg = &govcd.AdminOrg{AdminOrg: &types.AdminOrg{ID: "urn:vcloud:org:" + testOrgA, Name: "org-a"}}
+		vms, err := vdc.ListVMsContext(context.Background(), VMWithNameContaining("web"), VMWithDetails())
+		require.NoError(t, err)
+		require.Len(t, vms, 2)
+		assert.Equal(t, VM{
+			ID:                 "urn:vcloud:vm:1",
+			Name:               "web-1",
+			VAppID:             "urn:vcloud:vapp:1",
+			VAppName:           "web",
+			VDCID:              "urn:vcloud:vdc:" + testVDC1,
+			VDCName:            "vdc-1",
+			OrgID:              "urn:vcloud:org:" + testOrgA,
+			OrgName:            "org-a",
+			Status:             "POWERED_ON",
+			GuestOS:            "Ubuntu Linux (64-bit)",
+			CPUs:               2,
+			CoresPerSocket:     2,
+			Memory:             DataStorage(4096 * mb),
+			ProvisionedStorage: DataStorage(20480 * mb),
+			UsedStorage:        DataStorage(10240 * mb),
+			HardwareVersion:    19,
+			StorageProfile:     "fast",
+			CreatedAt:          time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+		}, vms[0])
+		assert.Equal(t, "web-2", vms[1].Name)
+		assert.Equal(t, uint64(1), vms[1].CoresPerSocket)
+		assert.Zero(t, vms[1].UsedStorage)
+	})
+	t.Run("vdcs", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 2)
+		vdcs := VDCs{*testQueryVDC(t, srv, testVDC1), *testQueryVDC(t, srv, testVDC2)}
+		vms, err := vdcs.ListVMsContext(context.Background(), VMWithNameMatching(regexp.MustCompile(`^(web|app)-`)), VMWithDetails())
+		require.NoError(t, err)
+		names := make([]string, 0, len(vms))
+		for _, vm := range vms {
+			names = append(names, vm.Name)
+		}
+		assert.Equal(t, []string{"web-1", "web-2", "app-1"}, names)
+		assert.Equal(t, "urn:vcloud:vdc:"+testVDC2, vms[2].VDCID)
+		assert.Equal(t, DataStorage(1024*mb), vms[2].UsedStorage)
+		assert.Empty(t, vdcs.ListVMs(VMPoweredOn(), VMWithNameContaining("none")))
+	})
+	t.Run("concurrency", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 2)
+		srv.delay = 20 * time.Millisecond
+		vdcs := VDCs{*testQueryVDC(t, srv, testVDC1), *testQueryVDC(t, srv, testVDC2)}
+		vms, err := vdcs.ListVMsContext(context.Background(), VMWithNameMatching(regexp.MustCompile(`^(web|app)-`)), VMWithDetails())
+		require.NoError(t, err)
+		assert.Len(t, vms, 3)
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		assert.LessOrEqual(t, srv.peak, vdcs[0].Client.concurrency())
+	})
+	t.Run("without details", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 2)
+		vdc := testQueryVDC(t, srv, testVDC1)
+		vms, err := vdc.ListVMsContext(context.Background())
+		require.NoError(t, err)
+		require.Len(t, vms, 3)
+		assert.Equal(t, "web-1", vms[0].Name)
+		assert.Equal(t, uint64(2), vms[0].CPUs)
+		assert.Equal(t, DataStorage(20480*mb), vms[0].ProvisionedStorage)
+		assert.Zero(t, vms[0].CoresPerSocket)
+		assert.Zero(t, vms[0].UsedStorage)
+		srv.mu.Lock()
+		defer srv.mu.Unlock()
+		for _, req := range srv.requests {
+			assert.NotContains(t, req, "/api/vApp/")
+		}
+	})
+	t.Run("deleted", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 2)
+		srv.notFound = []string{"/api/vApp/vm-2/metrics/current"}
+		vdc := testQueryVDC(t, srv, testVDC1)
+		vms, err := vdc.ListVMsContext(context.Background(), VMWithDetails())
+		require.NoError(t, err)
+		names := make([]string, 0, len(vms))
+		for _, vm := range vms {
+			names = append(names, vm.Name)
+		}
+		assert.Equal(t, []string{"web-1"}, names)
+	})
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 2)
+		srv.failing = []string{"/api/vApp/vm-2"}
+		vdc := testQueryVDC(t, srv, testVDC1)
+		_, err := vdc.ListVMsContext(context.Background(), VMWithDetails())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "urn:vcloud:vm:2")
+		assert.Nil(t, vdc.ListVMs(VMWithDetails()))
+		_, err = VDCs{*vdc}.ListVMsContext(context.Background(), VMWithDetails())
+		var vdcErr *VDCError
+		require.True(t, errors.As(err, &vdcErr))
+		assert.Equal(t, "urn:vcloud:vdc:"+testVDC1, vdcErr.ID)
+	})
+}
@@ -0,0 +1,77 @@
+package vcdusage
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/joomcode/errorx"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// VMResult is a VM streamed by VDC.VMs, or the error that ended the stream.
+type VMResult struct {
+	// VM is the VM, without the details retrieved by VMWithDetails.
+	VM *VM
+	// Record is the query record the VM was built from.
+	Record *types.QueryResultVMRecordType
+	Err    error
+}
+
+// VMs streams the VMs deployed in a vApp in the VDC that match all of the provided queries. VMs
+// are retrieved a page at a time, so the VMs of the VDC are never all held in memory. Queries that
+// vCloud can evaluate, such as those set by VMWithNameContaining, VMWithGuestOSContaining, and
+// VMPoweredOn, are sent to vCloud as query filters; regular expressions are evaluated as each page
+// arrives. If PoweredOn is false (default), VMs that are both powered on or off will be included.
+//
+// The channel is closed once every matching VM has been sent. If a page cannot be retrieved, the
+// error is sent as the last result. If ctx is cancelled, the channel is closed without sending any
+// further results, so callers should check ctx.Err() once the channel is closed, and must cancel
+// ctx if they stop receiving before it is closed.
+func (vdc *VDC) VMs(ctx context.Context, queries ...VMQuerySetter) <-chan VMResult {
+	results := make(chan VMResult)
+	ctx, span := vdc.startSpan(ctx, "VMs")
+	go func() {
+		defer close(results)
+		send := func(res VMResult) bool {
+			select {
+			case results <- res:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+		filter, rest := newVMQuery(queries...).fiql()
+		err := vdc.vmPages(ctx, filter, func(vms []*types.QueryResultVMRecordType) error {
+			for _, rec := range rest.filter(vms) {
+				if !send(VMResult{VM: vdc.vmOf(rec), Record: rec}) {
+					return ctx.Err()
+				}
+			}
+			return nil
+		})
+		endSpan(span, err)
+		if err != nil {
+			send(VMResult{Err: err})
+		}
+	}()
+	return results
+}
+
+// vmPages retrieves the VMs deployed in a vApp in the VDC that match filter, if set, a page at a
+// time, and calls fn with the VMs of each page.
+func (vdc *VDC) vmPages(ctx context.Context, filter string, fn func([]*types.QueryResultVMRecordType) error) error {
+	queryType := vdc.Client.VCD.Client.GetQueryType(types.QtVm)
+	conditions := fmt.Sprintf("%s;vdc==%s", types.VmQueryFilterOnlyDeployed, url.QueryEscape(vdc.Obj.Vdc.HREF))
+	if filter != "" {
+		conditions += ";" + filter
+	}
+	err := vdc.Client.queryPages(ctx, queryType, conditions, func(res *types.QueryResultRecordsType) error {
+		return fn(append(res.VMRecord, res.AdminVMRecord...))
+	})
+	if err != nil {
+		err = errorx.Decorate(err, "failed to query VMs for VDC '%s'", vdc.Obj.Vdc.ID)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package vcdusage
+
+import (
+	"context"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vmware/go-vcloud-director/v2/govcd"
+	"github.com/vmware/go-vcloud-director/v2/types/v56"
+)
+
+// testQueryVDC returns the VDC with id served by srv.
+func testQueryVDC(t *testing.T, srv *testQueryServer, id string) *VDC {
+	t.Helper()
+	obj := &govcd.Vdc{Vdc: &types.Vdc{ID: "urn:vcloud:vdc:" + id, HREF: srv.URL + "/api/vdc/" + id}}
+	return &VDC{Obj: obj, Client: srv.client(t)}
+}
+
+func Test_VMs(t *testing.T) {
+	t.Run("pages", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 1)
+		vdc := testQueryVDC(t, srv, testVDC1)
+		names := make([]string, 0)
+		for res := range vdc.VMs(context.Background()) {
+			require.NoError(t, res.Err)
+			assert.Equal(t, "urn:vcloud:vdc:"+testVDC1, res.VM.VDCID)
+			assert.Equal(t, urnOf("vm", res.Record.HREF), res.VM.ID)
+			names = append(names, res.VM.Name)
+		}
+		assert.Equal(t, []string{"web-1", "web-2", "db-1"}, names)
+		assert.Equal(t, 3, srv.queries("adminVM"))
+	})
+	t.Run("queries", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 2)
+		vdc := testQueryVDC(t, srv, testVDC1)
+		vms, err := vdc.queryVMs(context.Background(), VMPoweredOn(), VMWithNameContaining("web"))
+		require.NoError(t, err)
+		require.Len(t, vms, 1)
+		assert.Equal(t, "web-1", vms[0].Name)
+		count, err := vdc.VMCoreCountWithQueryContext(context.Background(), VMPoweredOn())
+		require.NoError(t, err)
+		assert.Equal(t, uint64(6), count)
+	})
+	t.Run("server-side", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 1)
+		vdc := testQueryVDC(t, srv, testVDC1)
+		vms, err := vdc.queryVMs(context.Background(), VMWithNameContaining("WEB"), VMPoweredOn())
+		require.NoError(t, err)
+		require.Len(t, vms, 1)
+		assert.Equal(t, "web-1", vms[0].Name)
+		assert.Equal(t, 1, srv.queries("adminVM"))
+		vms, err = vdc.queryVMs(context.Background(), VMWithNameContaining("-1"))
+		require.NoError(t, err)
+		require.Len(t, vms, 2)
+		assert.Equal(t, 3, srv.queries("adminVM"))
+		vms, err = vdc.queryVMs(context.Background(), VMWithNameMatching(regexp.MustCompile("^db-")))
+		require.NoError(t, err)
+		require.Len(t, vms, 1)
+		assert.Equal(t, "db-1", vms[0].Name)
+		assert.Equal(t, 6, srv.queries("adminVM"))
+	})
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 2)
+		srv.Close()
+		vdc := testQueryVDC(t, srv, testVDC1)
+		var last VMResult
+		for res := range vdc.VMs(context.Background()) {
+			last = res
+		}
+		assert.Nil(t, last.VM)
+		assert.Nil(t, last.Record)
+		assert.Error(t, last.Err)
+	})
+	t.Run("cancelled", func(t *testing.T) {
+		t.Parallel()
+		srv := newTestQueryServer(t, 1)
+		vdc := testQueryVDC(t, srv, testVDC1)
+		ctx, cancel := context.WithCancel(context.Background())
+		results := vdc.VMs(ctx)
+		first := <-results
+		require.NoError(t, first.Err)
+		cancel()
+		for range results {
+		}
+		assert.LessOrEqual(t, srv.queries("adminVM"), 2)
+	})
+}